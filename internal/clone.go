@@ -0,0 +1,47 @@
+package internal
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/prathoss/cards/pkg"
+)
+
+// CheckCanClone returns ForbiddenError when the fork would reveal cards the caller can not see,
+// cards of a fork can be drawn freely, so sealed decks are never cloned and hidden decks only by their owner
+func (d *Deck) CheckCanClone(token string) error {
+	if d.Sealed {
+		return pkg.NewForbiddenError(fmt.Sprintf("deck with ID %s is sealed and can not be cloned", d.ID))
+	}
+	return d.CheckCanReveal(token)
+}
+
+// Fork returns a new deck with the same cards in the same order, piles and metadata as the deck, referencing it as parent.
+// The fork can be modified even when the deck is closed and it can not be undone past its creation.
+// Fairness is not copied, revealing the server seed of the fork would reveal the order of the deck.
+func (d *Deck) Fork() Deck {
+	parentID := d.ID
+	fork := Deck{
+		ID:             uuid.New(),
+		ParentID:       &parentID,
+		Shuffled:       d.Shuffled,
+		Type:           d.Type,
+		Decks:          d.Decks,
+		Jokers:         d.Jokers,
+		Selection:      slices.Clone(d.Selection),
+		Cards:          slices.Clone(d.Cards),
+		Seed:           d.Seed,
+		Piles:          clonePiles(d.Piles),
+		Sealed:         d.Sealed,
+		MaxPeek:        d.MaxPeek,
+		Hidden:         d.Hidden,
+		OwnerTokenHash: d.OwnerTokenHash,
+		TTL:            d.TTL,
+		UndoDepth:      d.UndoDepth,
+		CreatedAt:      now(),
+		Version:        1,
+	}
+	fork.touch(fork.CreatedAt)
+	return fork
+}
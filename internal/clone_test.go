@@ -0,0 +1,55 @@
+package internal
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestDeck_Fork(t *testing.T) {
+	deck, err := NewDeck(DeckOptions{Shuffled: true, Hidden: true, OwnerToken: "token", TTL: time.Hour, UndoDepth: 2}, CryptoRandomSource{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := deck.DrawToPile("discard", 2); err != nil {
+		t.Fatal(err)
+	}
+	deck.pushSnapshot(deck.snapshot())
+	deck.Version = 5
+	deck.Close()
+
+	fork := deck.Fork()
+	if fork.ID == deck.ID || fork.ParentID == nil || *fork.ParentID != deck.ID {
+		t.Errorf("Deck.Fork() id = %s, parent = %v, want new id and parent %s", fork.ID, fork.ParentID, deck.ID)
+	}
+	if !slices.Equal(cardsToCodes(fork.Cards), cardsToCodes(deck.Cards)) {
+		t.Errorf("Deck.Fork() cards = %v, want %v", cardsToCodes(fork.Cards), cardsToCodes(deck.Cards))
+	}
+	if !slices.Equal(cardsToCodes(fork.Piles["discard"]), cardsToCodes(deck.Piles["discard"])) {
+		t.Errorf("Deck.Fork() discard pile = %v, want %v", fork.Piles["discard"], deck.Piles["discard"])
+	}
+	if !fork.IsOwner("token") || !fork.Hidden || fork.TTL != deck.TTL || fork.UndoDepth != deck.UndoDepth {
+		t.Errorf("Deck.Fork() did not copy metadata of the deck")
+	}
+	if fork.Closed || fork.Version != 1 || len(fork.Snapshots) != 0 || fork.ExpiresAt == nil {
+		t.Errorf("Deck.Fork() closed = %v, version = %d, snapshots = %d, expires at = %v, want a new deck",
+			fork.Closed, fork.Version, len(fork.Snapshots), fork.ExpiresAt)
+	}
+
+	fork.Cards[0] = Card{}
+	fork.Piles["discard"][0] = Card{}
+	if deck.Cards[0] == (Card{}) || deck.Piles["discard"][0] == (Card{}) {
+		t.Errorf("Deck.Fork() shares cards with the deck")
+	}
+}
+
+func TestDeck_Fork_Fair(t *testing.T) {
+	deck, err := NewDeck(DeckOptions{Fair: true}, CryptoRandomSource{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fork := deck.Fork(); fork.Fairness != nil {
+		t.Errorf("Deck.Fork() copied fairness of the deck")
+	}
+}
@@ -3,26 +3,114 @@ package internal
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"time"
+)
+
+const (
+	StorageMongo  = "mongo"
+	StorageMemory = "memory"
 )
 
 type Config struct {
-	Address         string
+	Address string
+	// Storage selects the DeckProcessor backend, either StorageMongo or StorageMemory
+	Storage         string
 	MongoConnection string
+	// AdminToken authorizes administrator requests, administration is disabled when empty
+	AdminToken string
+	// RandomSource selects the source of randomness, either RandomSourceCrypto or RandomSourceSeeded
+	RandomSource string
+	// RandomSeed initializes RandomSourceSeeded
+	RandomSeed uint64
+	// DeckTTL is how long decks are kept after their last modification, zero keeps decks forever
+	DeckTTL time.Duration
+	// UndoDepth is how many latest modifications of a deck can be undone, undo is disabled when zero
+	UndoDepth int
 }
 
+const (
+	// defaultDeckTTL is used when CARDS_DECK_TTL is not set
+	defaultDeckTTL = 30 * 24 * time.Hour
+	// defaultUndoDepth is used when CARDS_UNDO_DEPTH is not set
+	defaultUndoDepth = 10
+)
+
 func NewConfigFromEnv() (Config, error) {
 	address := os.Getenv("CARDS_ADDRESS")
 	if address == "" {
 		address = ":8080"
 	}
 
+	const storageEnvVar = "CARDS_STORAGE"
+	storage := os.Getenv(storageEnvVar)
+	switch storage {
+	case "":
+		storage = StorageMongo
+	case StorageMongo, StorageMemory:
+	default:
+		return Config{}, fmt.Errorf("%s environment variable has unknown value %q", storageEnvVar, storage)
+	}
+
 	const mongoConnectionEnvVar = "CARDS_MONGO_CONN_STR"
 	mongoConnection := os.Getenv(mongoConnectionEnvVar)
-	if mongoConnection == "" {
+	if storage == StorageMongo && mongoConnection == "" {
 		return Config{}, fmt.Errorf("%s environment variable is not set", mongoConnectionEnvVar)
 	}
+	const randomSourceEnvVar = "CARDS_RANDOM_SOURCE"
+	randomSource := os.Getenv(randomSourceEnvVar)
+	switch randomSource {
+	case "":
+		randomSource = RandomSourceCrypto
+	case RandomSourceCrypto, RandomSourceSeeded:
+	default:
+		return Config{}, fmt.Errorf("%s environment variable has unknown value %q", randomSourceEnvVar, randomSource)
+	}
+
+	const randomSeedEnvVar = "CARDS_RANDOM_SEED"
+	var randomSeed uint64
+	if randomSeedStr := os.Getenv(randomSeedEnvVar); randomSeedStr != "" {
+		var err error
+		randomSeed, err = strconv.ParseUint(randomSeedStr, 10, 64)
+		if err != nil {
+			return Config{}, fmt.Errorf("%s environment variable is not valid: %w", randomSeedEnvVar, err)
+		}
+	}
+
+	const deckTTLEnvVar = "CARDS_DECK_TTL"
+	deckTTL := defaultDeckTTL
+	if deckTTLStr := os.Getenv(deckTTLEnvVar); deckTTLStr != "" {
+		var err error
+		deckTTL, err = time.ParseDuration(deckTTLStr)
+		if err != nil {
+			return Config{}, fmt.Errorf("%s environment variable is not valid: %w", deckTTLEnvVar, err)
+		}
+		if deckTTL < 0 {
+			return Config{}, fmt.Errorf("%s environment variable should not be negative", deckTTLEnvVar)
+		}
+	}
+
+	const undoDepthEnvVar = "CARDS_UNDO_DEPTH"
+	undoDepth := defaultUndoDepth
+	if undoDepthStr := os.Getenv(undoDepthEnvVar); undoDepthStr != "" {
+		var err error
+		undoDepth, err = strconv.Atoi(undoDepthStr)
+		if err != nil {
+			return Config{}, fmt.Errorf("%s environment variable is not valid: %w", undoDepthEnvVar, err)
+		}
+		if undoDepth < 0 || undoDepth > MaxUndoDepth {
+			return Config{}, fmt.Errorf("%s environment variable should be between 0 and %d", undoDepthEnvVar, MaxUndoDepth)
+		}
+	}
+
 	return Config{
 		Address:         address,
+		Storage:         storage,
 		MongoConnection: mongoConnection,
+		AdminToken:      os.Getenv("CARDS_ADMIN_TOKEN"),
+		RandomSource:    randomSource,
+		RandomSeed:      randomSeed,
+		DeckTTL:         deckTTL,
+		UndoDepth:       undoDepth,
 	}, nil
 }
@@ -0,0 +1,72 @@
+package internal
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/prathoss/cards/pkg"
+)
+
+// CutOptions select where the deck is cut
+type CutOptions struct {
+	// Position is the number of cards moved from the top to the bottom, zero picks a random position
+	Position int
+	// Min and Max bound the random position, zero means 1 and the number of remaining cards minus 1 respectively
+	Min int
+	Max int
+}
+
+// Cut moves the top Position cards under the rest of the deck and returns the position of the cut.
+// The card at the cut point, the top card of the lower packet, becomes the top card of the deck.
+// Sealed decks can not be cut, repeated cuts and peeks would reveal their whole order.
+func (d *Deck) Cut(options CutOptions, random RandomSource) (int, error) {
+	if d.Sealed {
+		return 0, pkg.NewForbiddenError(fmt.Sprintf("deck with ID %s is sealed and can not be cut", d.ID))
+	}
+	if len(d.Cards) < 2 {
+		return 0, newNotEnoughCardsError()
+	}
+
+	position := options.Position
+	if position == 0 {
+		low, high := max(options.Min, 1), options.Max
+		if high == 0 {
+			high = len(d.Cards) - 1
+		}
+		if low > high || high > len(d.Cards)-1 {
+			return 0, newInvalidCutBoundsError(len(d.Cards))
+		}
+		offset, err := d.randomSource(random).IntN(high - low + 1)
+		if err != nil {
+			return 0, err
+		}
+		position = low + offset
+	}
+	if position < 1 || position > len(d.Cards)-1 {
+		return 0, newInvalidCutPositionError(len(d.Cards))
+	}
+
+	d.Cards = slices.Concat(d.Cards[position:], d.Cards[:position])
+	return position, nil
+}
+
+type CutResponse struct {
+	CreateDeckResponse
+	Position int `json:"position"`
+	// Card is the card at the cut point, it is only revealed on request
+	Card *CardResponse `json:"card,omitempty"`
+}
+
+func newInvalidCutPositionError(remaining int) *pkg.BadRequestError {
+	return pkg.NewBadRequestError(pkg.InvalidParam{
+		Name:   "position",
+		Reason: fmt.Sprintf("position should be between 1 and %d", remaining-1),
+	})
+}
+
+func newInvalidCutBoundsError(remaining int) *pkg.BadRequestError {
+	return pkg.NewBadRequestError(pkg.InvalidParam{
+		Name:   "max",
+		Reason: fmt.Sprintf("min should not be greater than max and max should be at most %d", remaining-1),
+	})
+}
@@ -0,0 +1,117 @@
+package internal
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/prathoss/cards/pkg"
+)
+
+func TestDeck_Cut_Sealed(t *testing.T) {
+	deck := Deck{Cards: spades(5), Sealed: true}
+
+	_, err := deck.Cut(CutOptions{Position: 1}, CryptoRandomSource{})
+	var forbiddenError *pkg.ForbiddenError
+	if !errors.As(err, &forbiddenError) {
+		t.Errorf("Deck.Cut() error = %v, want ForbiddenError", err)
+	}
+	if got, want := cardsToCodes(deck.Cards), cardsToCodes(spades(5)); !slices.Equal(got, want) {
+		t.Errorf("Deck.Cut() changed the sealed deck to %v", got)
+	}
+}
+
+func TestDeck_Cut(t *testing.T) {
+	tests := []struct {
+		name         string
+		cards        []Card
+		options      CutOptions
+		random       RandomSource
+		wantPosition int
+		want         []string
+		wantErr      bool
+	}{
+		{
+			name:         "Position",
+			cards:        spades(6),
+			options:      CutOptions{Position: 2},
+			wantPosition: 2,
+			want:         []string{"3S", "4S", "5S", "6S", "AS", "2S"},
+		},
+		{
+			name:         "LastPosition",
+			cards:        spades(6),
+			options:      CutOptions{Position: 5},
+			wantPosition: 5,
+			want:         []string{"6S", "AS", "2S", "3S", "4S", "5S"},
+		},
+		{
+			name:         "Random",
+			cards:        spades(6),
+			random:       NewFixtureRandomSource(3),
+			wantPosition: 4,
+			want:         []string{"5S", "6S", "AS", "2S", "3S", "4S"},
+		},
+		{
+			name:         "RandomWithinBounds",
+			cards:        spades(6),
+			options:      CutOptions{Min: 2, Max: 3},
+			random:       NewFixtureRandomSource(5),
+			wantPosition: 3,
+			want:         []string{"4S", "5S", "6S", "AS", "2S", "3S"},
+		},
+		{
+			name:    "PositionOutOfDeck",
+			cards:   spades(6),
+			options: CutOptions{Position: 6},
+			wantErr: true,
+		},
+		{
+			name:    "MaxOutOfDeck",
+			cards:   spades(6),
+			options: CutOptions{Max: 6},
+			wantErr: true,
+		},
+		{
+			name:    "MinAboveRemaining",
+			cards:   spades(6),
+			options: CutOptions{Min: 6},
+			wantErr: true,
+		},
+		{
+			name:    "SingleCard",
+			cards:   spades(1),
+			options: CutOptions{},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deck := Deck{Cards: tt.cards}
+			random := tt.random
+			if random == nil {
+				random = CryptoRandomSource{}
+			}
+
+			position, err := deck.Cut(tt.options, random)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Deck.Cut() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				var badRequestError *pkg.BadRequestError
+				if !errors.As(err, &badRequestError) {
+					t.Errorf("expected BadRequestError, got %v", err)
+				}
+				return
+			}
+
+			if position != tt.wantPosition {
+				t.Errorf("Deck.Cut() position = %d, want %d", position, tt.wantPosition)
+			}
+			if got := cardsToCodes(deck.Cards); !slices.Equal(got, tt.want) {
+				t.Errorf("Deck.Cut() cards = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,15 +1,21 @@
 package internal
 
 import (
-	"crypto/rand"
 	"fmt"
-	"math/big"
+	"slices"
 	"strconv"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/prathoss/cards/pkg"
 )
 
+const (
+	PositionTop    = "top"
+	PositionBottom = "bottom"
+	PositionRandom = "random"
+)
+
 const (
 	CardSuitClubs    = "CLUBS"
 	CardSuitDiamonds = "DIAMONDS"
@@ -29,6 +35,20 @@ const (
 	CardValueJack  = "JACK"
 	CardValueQueen = "QUEEN"
 	CardValueKing  = "KING"
+
+	// suits and face values of the Spanish deck
+	CardSuitCoins    = "OROS"
+	CardSuitCups     = "COPAS"
+	CardSuitSwords   = "ESPADAS"
+	CardSuitBatons   = "BASTOS"
+	CardValueSota    = "SOTA"
+	CardValueCaballo = "CABALLO"
+	CardValueRey     = "REY"
+
+	// CardValueJoker is the value of jokers, they have no suit and carry their color in place of it
+	CardValueJoker = "JOKER"
+	CardSuitRed    = "RED"
+	CardSuitBlack  = "BLACK"
 )
 
 type Card struct {
@@ -36,7 +56,24 @@ type Card struct {
 	Suit  string `json:"suit"`
 }
 
+// cardsToCodes returns codes of the cards in the same order
+func cardsToCodes(cards []Card) []string {
+	codes := make([]string, 0, len(cards))
+	for _, c := range cards {
+		codes = append(codes, c.Code())
+	}
+	return codes
+}
+
 func (c Card) Code() string {
+	if c.Value == CardValueJoker {
+		// jokers use X as their value, J is already taken by jacks
+		v := "X"
+		if len(c.Suit) > 0 {
+			v += string(c.Suit[0])
+		}
+		return v
+	}
 	v := ""
 	s := ""
 	if _, err := strconv.Atoi(c.Value); err == nil {
@@ -53,38 +90,248 @@ func (c Card) Code() string {
 type Deck struct {
 	ID       uuid.UUID `json:"deck_id" bson:"_id"`
 	Shuffled bool      `json:"shuffled" bson:"shuffled,omitempty"`
-	Cards    []Card    `json:"cards" bson:"cards,omitempty"`
+	// Type is the name of the DeckTemplate the deck was built from
+	Type string `json:"type" bson:"type,omitempty"`
+	// Decks is the number of standard decks the deck was built from
+	Decks  int  `json:"decks" bson:"decks,omitempty"`
+	Jokers bool `json:"jokers" bson:"jokers,omitempty"`
+	// Selection holds card codes the deck was restricted to on creation, empty when the deck is full
+	Selection []string `json:"-" bson:"selection,omitempty"`
+	Cards     []Card   `json:"cards" bson:"cards,omitempty"`
+	// ParentID references the deck this deck was cloned from, see Deck.Fork
+	ParentID *uuid.UUID `json:"parent_id,omitempty" bson:"parent_id,omitempty"`
+	// Seed makes every random operation on the deck deterministic, see Deck.random
+	Seed *uint64 `json:"-" bson:"seed,omitempty"`
+	// Piles holds named piles of cards drawn from the deck, like discard piles or player hands
+	Piles map[string][]Card `json:"piles" bson:"piles,omitempty"`
+	// Closed decks can not be modified anymore
+	Closed bool `json:"closed" bson:"closed,omitempty"`
+	// Sealed decks can not be opened, their cards can only be peeked at or drawn
+	Sealed bool `json:"sealed" bson:"sealed,omitempty"`
+	// MaxPeek is the maximal number of cards peeked at once on a sealed deck, see Deck.maxPeek
+	MaxPeek int `json:"max_peek,omitempty" bson:"max_peek,omitempty"`
+	// Hidden decks are opened without their cards, unless the caller holds the owner token
+	Hidden bool `json:"hidden" bson:"hidden,omitempty"`
+	// OwnerTokenHash is the hash of the owner token of a hidden deck, see Deck.IsOwner
+	OwnerTokenHash string    `json:"-" bson:"owner_token_hash,omitempty"`
+	Fairness       *Fairness `json:"fairness,omitempty" bson:"fairness,omitempty"`
+	// CreatedAt is zero for decks created before their lifecycle was tracked
+	CreatedAt time.Time `json:"created_at" bson:"created_at,omitempty"`
+	// LastUsedAt is the time of the last modification of the deck
+	LastUsedAt time.Time `json:"last_used_at" bson:"last_used_at,omitempty"`
+	// ExpiresAt is LastUsedAt plus TTL, decks without TTL never expire
+	ExpiresAt *time.Time    `json:"expires_at,omitempty" bson:"expires_at,omitempty"`
+	TTL       time.Duration `json:"-" bson:"ttl,omitempty"`
+	// Snapshots hold states of the deck before its latest modifications, the latest first, see Deck.Undo
+	Snapshots []DeckSnapshot `json:"-" bson:"snapshots,omitempty"`
+	// UndoDepth is the maximal number of Snapshots, modifications can not be undone when it is zero
+	UndoDepth int `json:"-" bson:"undo_depth,omitempty"`
+	// Version is incremented by every modification of the deck
+	Version int64 `json:"-" bson:"version"`
+}
+
+// DeckOptions describe the composition of a new deck
+type DeckOptions struct {
+	// Type is the name of the DeckTemplate to build the deck from, empty means the standard deck
+	Type string
+	// Cards restricts the deck to the given card codes, full deck is generated when empty
+	Cards    []string
+	Shuffled bool
+	// ShuffleMethod selects how a shuffled deck is shuffled
+	ShuffleMethod ShuffleMethod
+	// Jokers adds red and black jokers to a full deck, jokers in Cards are used regardless of it
+	Jokers bool
+	// Decks is the number of decks combined into a single shoe, zero means a single deck
+	Decks int
+	// Seed makes the shuffle reproducible, cryptographically secure randomness is used when nil
+	Seed *uint64
+	// Sealed forbids opening the deck
+	Sealed bool
+	// MaxPeek limits peeks at a sealed deck, DefaultMaxPeek is used when zero
+	MaxPeek int
+	// Hidden hides cards of the opened deck from everybody except the holder of OwnerToken
+	Hidden     bool
+	OwnerToken string
+	// TTL is how long the deck is kept after its last modification, zero keeps the deck forever
+	TTL time.Duration
+	// UndoDepth is how many latest modifications of the deck can be undone
+	UndoDepth int
+	// Fair makes the deck provably fair, it is shuffled by Deck.SeedFairly once the client seed is sent
+	Fair bool
 }
 
-func NewDeck(cardCodes []string, shuffled bool) (Deck, error) {
-	cards := generateCards(cardCodes)
+// MaxDecks is the maximal number of decks combined into a single shoe
+const MaxDecks = 8
+
+// DefaultMaxPeek is the maximal number of cards peeked at once on a sealed deck created without a limit
+const DefaultMaxPeek = 1
+
+func NewDeck(options DeckOptions, random RandomSource) (Deck, error) {
+	template, ok := LookupDeckTemplate(options.Type)
+	if !ok {
+		return Deck{}, newUnknownDeckTypeError(options.Type)
+	}
+	decks := max(options.Decks, 1)
 	deck := Deck{
-		ID:       uuid.New(),
-		Shuffled: shuffled,
-		Cards:    cards,
+		ID:        uuid.New(),
+		Shuffled:  options.Shuffled,
+		Type:      template.Name,
+		Decks:     decks,
+		Jokers:    options.Jokers,
+		Selection: options.Cards,
+		Seed:      options.Seed,
+		Sealed:    options.Sealed,
+		Hidden:    options.Hidden,
+		Cards:     composeCards(template, options.Cards, options.Jokers, decks),
+		TTL:       options.TTL,
+		UndoDepth: options.UndoDepth,
+		CreatedAt: now(),
+		Version:   1,
 	}
-	if shuffled {
-		if err := deck.ShuffleCards(); err != nil {
+	deck.touch(deck.CreatedAt)
+	if options.Sealed {
+		deck.MaxPeek = DefaultMaxPeek
+		if options.MaxPeek > 0 {
+			deck.MaxPeek = options.MaxPeek
+		}
+	}
+	if options.OwnerToken != "" {
+		deck.OwnerTokenHash = hashOwnerToken(options.OwnerToken)
+	}
+	if options.Fair {
+		// the deck is shuffled once the client seed is sent, see Deck.SeedFairly
+		fairness, err := newFairness()
+		if err != nil {
+			return deck, err
+		}
+		deck.Fairness = &fairness
+		deck.Shuffled = false
+		return deck, nil
+	}
+	if options.Shuffled {
+		if err := deck.ShuffleCardsWith(options.ShuffleMethod, random); err != nil {
 			return deck, err
 		}
 	}
 	return deck, nil
 }
 
+// Close marks the deck as closed, which reveals the seeds of a provably fair deck
+func (d *Deck) Close() {
+	d.Closed = true
+}
+
+// checkNotClosed returns ConflictError when the deck is closed
+func (d *Deck) checkNotClosed() error {
+	if d.Closed {
+		return newDeckClosedError(d.ID)
+	}
+	return nil
+}
+
+// CheckCanOpen returns ForbiddenError when the deck is sealed
+func (d *Deck) CheckCanOpen() error {
+	if d.Sealed {
+		return pkg.NewForbiddenError(fmt.Sprintf("deck with ID %s is sealed and can not be opened", d.ID))
+	}
+	return nil
+}
+
+// Peek returns count cards from the top or the bottom of the deck without drawing them.
+// Sealed decks allow peeking at most at maxPeek cards, so their order can not be revealed at once.
+func (d *Deck) Peek(count int, position string) ([]Card, error) {
+	if position == PositionRandom {
+		return nil, pkg.NewBadRequestError(pkg.InvalidParam{
+			Name:   "position",
+			Reason: "only top and bottom cards can be peeked at",
+		})
+	}
+	if d.Sealed && count > d.maxPeek() {
+		return nil, pkg.NewBadRequestError(pkg.InvalidParam{
+			Name:   "count",
+			Reason: fmt.Sprintf("at most %d cards of a sealed deck can be peeked at", d.maxPeek()),
+		})
+	}
+	cards, _, err := takeCards(d.Cards, CardSelector{Count: count, Position: position}, "deck", nil)
+	if err != nil {
+		return nil, err
+	}
+	return slices.Clone(cards), nil
+}
+
+// maxPeek returns MaxPeek of the deck, sealed decks created before the limit was stored use DefaultMaxPeek
+func (d *Deck) maxPeek() int {
+	if d.MaxPeek > 0 {
+		return d.MaxPeek
+	}
+	return DefaultMaxPeek
+}
+
+// Composition returns all cards the deck was created with, in their initial unshuffled order
+func (d *Deck) Composition() []Card {
+	template, ok := LookupDeckTemplate(d.Type)
+	if !ok {
+		return nil
+	}
+	return composeCards(template, d.Selection, d.Jokers, max(d.Decks, 1))
+}
+
+// ShuffleOptions describe a reshuffle of an existing deck
+type ShuffleOptions struct {
+	// ReturnDrawn returns drawn cards to the deck before shuffling
+	ReturnDrawn bool
+	// Seed replaces the seed of the deck, so the shuffle can be replayed
+	Seed *uint64
+	// Method selects how the cards are shuffled
+	Method ShuffleMethod
+}
+
+// Shuffle reshuffles the remaining cards and marks the deck as shuffled
+func (d *Deck) Shuffle(options ShuffleOptions, random RandomSource) error {
+	if options.Seed != nil {
+		d.Seed = options.Seed
+	}
+	if options.ReturnDrawn {
+		d.ReturnDrawnCards()
+	}
+	if err := d.ShuffleCardsWith(options.Method, random); err != nil {
+		return err
+	}
+	d.Shuffled = true
+	return nil
+}
+
 // ShuffleCards shuffles cards in deck (in place) using Fisher-Yates' algorithm
-func (d *Deck) ShuffleCards() error {
-	for i := len(d.Cards) - 1; i > 0; i-- {
-		jBig, err := rand.Int(rand.Reader, big.NewInt(int64(i)+1))
+func (d *Deck) ShuffleCards(random RandomSource) error {
+	return shuffleCards(d.Cards, d.randomSource(random))
+}
+
+// randomSource returns the source of randomness for a single operation on the deck.
+// Seeded decks replace random with a SeededRandomSource using the deck version as its stream,
+// so repeating the same operations on a deck created with the same seed yields the same results.
+func (d *Deck) randomSource(random RandomSource) RandomSource {
+	if d.Seed == nil {
+		return random
+	}
+	return NewSeededRandomSource(*d.Seed, uint64(d.Version))
+}
+
+// shuffleCards shuffles cards (in place) using Fisher-Yates' algorithm
+func shuffleCards(cards []Card, random RandomSource) error {
+	for i := len(cards) - 1; i > 0; i-- {
+		j, err := random.IntN(i + 1)
 		if err != nil {
 			return err
 		}
-		j := int(jBig.Int64())
-		d.Cards[i], d.Cards[j] = d.Cards[j], d.Cards[i]
+		cards[i], cards[j] = cards[j], cards[i]
 	}
 	return nil
 }
 
 func (d *Deck) DrawCards(count int) ([]Card, error) {
+	if count < 0 {
+		return nil, newNegativeCountError()
+	}
 	if count > len(d.Cards) {
 		return nil, newNotEnoughCardsError()
 	}
@@ -94,15 +341,202 @@ func (d *Deck) DrawCards(count int) ([]Card, error) {
 	return cards, nil
 }
 
-// generateCards generates a slice of cards based on the provided codes.
-// If the codes slice is empty, it generates all possible combinations of cards.
+// Draw removes cards matching the selector from the deck and returns them
+func (d *Deck) Draw(selector CardSelector, random RandomSource) ([]Card, error) {
+	cards, rest, err := takeCards(d.Cards, selector, "deck", d.randomSource(random))
+	if err != nil {
+		return nil, err
+	}
+	d.Cards = rest
+	return cards, nil
+}
+
+// ReturnCards puts cards with the given codes back to the deck at the position.
+// Every card has to be part of the deck composition and must not be in the deck already.
+func (d *Deck) ReturnCards(codes []string, position string, random RandomSource) error {
+	cards, err := d.cardsToReturn(codes)
+	if err != nil {
+		return err
+	}
+
+	switch position {
+	case PositionTop:
+		d.Cards = append(cards, d.Cards...)
+	case PositionBottom:
+		d.Cards = append(d.Cards, cards...)
+	case PositionRandom:
+		random := d.randomSource(random)
+		for _, card := range cards {
+			i, err := random.IntN(len(d.Cards) + 1)
+			if err != nil {
+				return err
+			}
+			d.Cards = slices.Insert(d.Cards, i, card)
+		}
+	default:
+		return newUnknownPositionError(position)
+	}
+	return nil
+}
+
+// ReturnDrawnCards puts all cards of the deck composition which are neither in the deck nor in its piles
+// back to the bottom of the deck
+func (d *Deck) ReturnDrawnCards() {
+	present := make(map[string]int, len(d.Cards))
+	for _, c := range d.heldCards() {
+		present[c.Code()]++
+	}
+	for _, c := range d.Composition() {
+		if present[c.Code()] > 0 {
+			present[c.Code()]--
+			continue
+		}
+		d.Cards = append(d.Cards, c)
+	}
+}
+
+// cardsToReturn validates that cards with the codes can be returned to the deck and returns them
+func (d *Deck) cardsToReturn(codes []string) ([]Card, error) {
+	composition := d.Composition()
+	cardsByCode := make(map[string]Card, len(composition))
+	available := make(map[string]int, len(composition))
+	for _, c := range composition {
+		cardsByCode[c.Code()] = c
+		available[c.Code()]++
+	}
+	for _, c := range d.heldCards() {
+		available[c.Code()]--
+	}
+
+	var invalidParams []pkg.InvalidParam
+	cards := make([]Card, 0, len(codes))
+	for _, code := range codes {
+		card, ok := cardsByCode[code]
+		if !ok {
+			invalidParams = append(invalidParams, pkg.InvalidParam{
+				Name:   "cards",
+				Reason: fmt.Sprintf("card %s does not belong to the deck", code),
+			})
+			continue
+		}
+		if available[code] < 1 {
+			invalidParams = append(invalidParams, pkg.InvalidParam{
+				Name:   "cards",
+				Reason: fmt.Sprintf("card %s is already in the deck or one of its piles", code),
+			})
+			continue
+		}
+		available[code]--
+		cards = append(cards, card)
+	}
+	if len(invalidParams) > 0 {
+		return nil, pkg.NewBadRequestError(invalidParams...)
+	}
+	return cards, nil
+}
+
+// heldCards returns cards in the deck followed by cards in all its piles
+func (d *Deck) heldCards() []Card {
+	cards := slices.Clone(d.Cards)
+	for _, pile := range d.Piles {
+		cards = append(cards, pile...)
+	}
+	return cards
+}
+
+// CardSelector describes which cards should be drawn
+type CardSelector struct {
+	// Count of cards drawn from Position
+	Count    int
+	Position string
+	// Codes selects exactly the cards to draw, Count and Position are ignored when it is set
+	Codes []string
+}
+
+// takeCards removes cards matching the selector from cards.
+// It returns the taken cards and the cards left, source names the card holder in errors.
+func takeCards(cards []Card, selector CardSelector, source string, random RandomSource) ([]Card, []Card, error) {
+	if len(selector.Codes) > 0 {
+		return takeCardsByCode(cards, selector.Codes, source)
+	}
+	if selector.Count < 0 {
+		return nil, nil, newNegativeCountError()
+	}
+	if selector.Count > len(cards) {
+		return nil, nil, newNotEnoughCardsInError(source)
+	}
+
+	switch selector.Position {
+	case PositionTop, "":
+		return cards[:selector.Count], cards[selector.Count:], nil
+	case PositionBottom:
+		split := len(cards) - selector.Count
+		return cards[split:], cards[:split], nil
+	case PositionRandom:
+		rest := slices.Clone(cards)
+		taken := make([]Card, 0, selector.Count)
+		for range selector.Count {
+			i, err := random.IntN(len(rest))
+			if err != nil {
+				return nil, nil, err
+			}
+			taken = append(taken, rest[i])
+			rest = slices.Delete(rest, i, i+1)
+		}
+		return taken, rest, nil
+	default:
+		return nil, nil, newUnknownPositionError(selector.Position)
+	}
+}
+
+// takeCardsByCode removes the first card matching each of the codes from cards.
+// All missing cards are reported in a single error.
+func takeCardsByCode(cards []Card, codes []string, source string) ([]Card, []Card, error) {
+	rest := slices.Clone(cards)
+	taken := make([]Card, 0, len(codes))
+	var invalidParams []pkg.InvalidParam
+	for _, code := range codes {
+		i := slices.IndexFunc(rest, func(c Card) bool {
+			return c.Code() == code
+		})
+		if i < 0 {
+			invalidParams = append(invalidParams, pkg.InvalidParam{
+				Name:   "cards",
+				Reason: fmt.Sprintf("card %s is not in the %s", code, source),
+			})
+			continue
+		}
+		taken = append(taken, rest[i])
+		rest = slices.Delete(rest, i, i+1)
+	}
+	if len(invalidParams) > 0 {
+		return nil, nil, pkg.NewBadRequestError(invalidParams...)
+	}
+	return taken, rest, nil
+}
+
+// composeCards generates cards of the given number of decks, each restricted to the codes
+func composeCards(template DeckTemplate, codes []string, jokers bool, decks int) []Card {
+	cards := make([]Card, 0)
+	for range decks {
+		cards = append(cards, generateCards(template, codes, jokers)...)
+	}
+	return cards
+}
+
+// generateCards generates a slice of cards of the template based on the provided codes.
+// If the codes slice is empty, it generates all possible combinations of cards, followed by jokers when requested.
 // It uses generateAllCardsCombinationsByCode to get the mapping of codes to cards.
 // Unknown card codes will be ignored
-func generateCards(codes []string) []Card {
+func generateCards(template DeckTemplate, codes []string, jokers bool) []Card {
 	if len(codes) == 0 {
-		return generateAllCardsCombinations()
+		cards := generateAllCardsCombinations(template)
+		if jokers {
+			cards = append(cards, generateJokers()...)
+		}
+		return cards
 	}
-	cardsByCode := generateAllCardsCombinationsByCode()
+	cardsByCode := generateAllCardsCombinationsByCode(template)
 	cards := make([]Card, 0, len(codes))
 	for _, code := range codes {
 		if card, ok := cardsByCode[code]; ok {
@@ -112,11 +546,11 @@ func generateCards(codes []string) []Card {
 	return cards
 }
 
-// generateAllCardsCombinationsByCode generates a mapping of card codes to cards.
-// It uses generateAllCardsCombinations to get all possible combinations of cards.
+// generateAllCardsCombinationsByCode generates a mapping of card codes to cards of the template.
+// It uses generateAllCardsCombinations and generateJokers to get all possible cards.
 // The resulting map maps each code to its corresponding card.
-func generateAllCardsCombinationsByCode() map[string]Card {
-	cards := generateAllCardsCombinations()
+func generateAllCardsCombinationsByCode(template DeckTemplate) map[string]Card {
+	cards := append(generateAllCardsCombinations(template), generateJokers()...)
 	cardsByCode := make(map[string]Card)
 	for _, c := range cards {
 		cardsByCode[c.Code()] = c
@@ -124,34 +558,77 @@ func generateAllCardsCombinationsByCode() map[string]Card {
 	return cardsByCode
 }
 
-// generateAllCardsCombinations generates all possible combinations of cards.
+// generateAllCardsCombinations generates all possible combinations of cards of the template.
 // It creates cards for each possible suit and value combination and returns them as a slice.
-func generateAllCardsCombinations() []Card {
-	suits := []string{CardSuitClubs, CardSuitDiamonds, CardSuitHearths, CardSuitSpades}
-	values := []string{CardValueAce, CardValueTwo, CardValueThree, CardValueFour, CardValueFive, CardValueSix, CardValueSeven, CardValueEight, CardValueNine, CardValueTen, CardValueJack, CardValueQueen, CardValueKing}
-	cards := make([]Card, 0, len(suits)*len(values))
-	for _, suit := range suits {
-		for _, value := range values {
-			cards = append(cards, Card{Value: value, Suit: suit})
+// Every card is repeated as many times as the template requires.
+func generateAllCardsCombinations(template DeckTemplate) []Card {
+	copies := max(template.Copies, 1)
+	cards := make([]Card, 0, len(template.Suits)*len(template.Values)*copies)
+	for _, suit := range template.Suits {
+		for _, value := range template.Values {
+			for range copies {
+				cards = append(cards, Card{Value: value, Suit: suit})
+			}
 		}
 	}
 	return cards
 }
 
+// generateJokers generates the red and the black joker.
+func generateJokers() []Card {
+	return []Card{
+		{Value: CardValueJoker, Suit: CardSuitRed},
+		{Value: CardValueJoker, Suit: CardSuitBlack},
+	}
+}
+
 type CreateDeckResponse struct {
-	ID        uuid.UUID `json:"deck_id"`
-	Shuffled  bool      `json:"shuffled"`
-	Remaining int       `json:"remaining"`
+	ID        uuid.UUID         `json:"deck_id"`
+	ParentID  *uuid.UUID        `json:"parent_id,omitempty"`
+	Shuffled  bool              `json:"shuffled"`
+	Type      string            `json:"type"`
+	Decks     int               `json:"decks"`
+	Remaining int               `json:"remaining"`
+	Closed    bool              `json:"closed"`
+	Sealed    bool              `json:"sealed"`
+	Hidden    bool              `json:"hidden"`
+	Fairness  *FairnessResponse `json:"fairness,omitempty"`
+	// Seed is only revealed to administrators, see Server.isAdmin
+	Seed *uint64 `json:"seed,omitempty"`
+	// OwnerToken of a hidden deck is only returned when the deck is created
+	OwnerToken string     `json:"owner_token,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt time.Time  `json:"last_used_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
 }
 
 func NewCreateDeckResponse(deck Deck) CreateDeckResponse {
 	return CreateDeckResponse{
-		ID:        deck.ID,
-		Shuffled:  deck.Shuffled,
-		Remaining: len(deck.Cards),
+		ID:       deck.ID,
+		ParentID: deck.ParentID,
+		Shuffled: deck.Shuffled,
+		Type:     deckType(deck),
+		// decks created before multi-deck shoes do not store the count
+		Decks:      max(deck.Decks, 1),
+		Remaining:  len(deck.Cards),
+		Closed:     deck.Closed,
+		Sealed:     deck.Sealed,
+		Hidden:     deck.Hidden,
+		Fairness:   NewFairnessResponse(deck),
+		CreatedAt:  deck.CreatedAt,
+		LastUsedAt: deck.LastUsedAt,
+		ExpiresAt:  deck.ExpiresAt,
 	}
 }
 
+// deckType returns type of the deck, decks created before deck types were introduced are standard
+func deckType(deck Deck) string {
+	if deck.Type == "" {
+		return DeckTypeStandard
+	}
+	return deck.Type
+}
+
 type OpenDeckResponse struct {
 	CreateDeckResponse
 	Cards []CardResponse `json:"cards"`
@@ -192,8 +669,34 @@ func NewCardResponse(c Card) CardResponse {
 }
 
 func newNotEnoughCardsError() *pkg.BadRequestError {
+	return newNotEnoughCardsInError("deck")
+}
+
+func newNotEnoughCardsInError(source string) *pkg.BadRequestError {
+	return pkg.NewBadRequestError(pkg.InvalidParam{
+		Name:   source,
+		Reason: fmt.Sprintf("%s does not have enough cards", source),
+	})
+}
+
+func newNegativeCountError() *pkg.BadRequestError {
 	return pkg.NewBadRequestError(pkg.InvalidParam{
-		Name:   "deck",
-		Reason: "deck does not have enough cards",
+		Name:   "count",
+		Reason: "count can not be negative",
 	})
 }
+
+func newDeckClosedError(deckID uuid.UUID) *pkg.ConflictError {
+	return pkg.NewConflictError(fmt.Sprintf("deck with ID %s is closed", deckID))
+}
+
+func newUnknownPositionError(position string) *pkg.BadRequestError {
+	return pkg.NewBadRequestError(pkg.InvalidParam{
+		Name:   "position",
+		Reason: unknownPositionReason(position),
+	})
+}
+
+func unknownPositionReason(position string) string {
+	return fmt.Sprintf("unknown position %q, supported positions are %s, %s and %s", position, PositionTop, PositionBottom, PositionRandom)
+}
@@ -4,37 +4,85 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/prathoss/cards/pkg"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// DeckProcessor stores decks, modifications and deletions of decks are restricted by WithIfMatch of their context
 type DeckProcessor interface {
-	Create(ctx context.Context, cardsCodes []string, shuffled bool) (Deck, error)
+	Create(ctx context.Context, options DeckOptions) (Deck, error)
 	Get(ctx context.Context, deckID uuid.UUID) (Deck, error)
-	DrawCards(ctx context.Context, deckID uuid.UUID, count int) ([]Card, error)
+	// Clone creates a new deck with the current state of the deck, see Deck.Fork
+	Clone(ctx context.Context, deckID uuid.UUID) (Deck, error)
+	// DrawCards removes cards matching the selector from the deck, it returns the updated deck and the drawn cards
+	DrawCards(ctx context.Context, deckID uuid.UUID, selector CardSelector) (Deck, []Card, error)
+	// ReturnCards puts cards with the codes back to the deck at the position and returns the updated deck
+	ReturnCards(ctx context.Context, deckID uuid.UUID, codes []string, position string) (Deck, error)
+	// Shuffle shuffles remaining cards of the deck, drawn cards are returned to the deck first when requested.
+	// Seed in options is stored on the deck and makes the shuffle reproducible.
+	Shuffle(ctx context.Context, deckID uuid.UUID, options ShuffleOptions) (Deck, error)
+	// DrawToPile draws count cards from the deck to the named pile and returns the updated deck
+	DrawToPile(ctx context.Context, deckID uuid.UUID, pile string, count int) (Deck, error)
+	// Deal deals count cards to each of the hands round-robin in a single update,
+	// it returns the updated deck and cards dealt to each hand in the order of hands
+	Deal(ctx context.Context, deckID uuid.UUID, hands []string, count int) (Deck, [][]Card, error)
+	DrawFromPile(ctx context.Context, deckID uuid.UUID, pile string, selector CardSelector) (Deck, []Card, error)
+	ShufflePile(ctx context.Context, deckID uuid.UUID, pile string) (Deck, error)
+	// Cut moves cards from the top of the deck to its bottom, it returns the updated deck and the number of moved cards
+	Cut(ctx context.Context, deckID uuid.UUID, options CutOptions) (Deck, int, error)
+	// Undo restores the deck to the state before its latest modification which was not undone yet
+	Undo(ctx context.Context, deckID uuid.UUID) (Deck, error)
+	// SeedFairness shuffles a provably fair deck with the client seed, see Deck.SeedFairly.
+	// Provably fair decks can not be modified in any other way than closing until they are seeded.
+	SeedFairness(ctx context.Context, deckID uuid.UUID, clientSeed string) (Deck, error)
+	// Close prevents any further modification of the deck
+	Close(ctx context.Context, deckID uuid.UUID) (Deck, error)
+	// Delete removes the deck and its history permanently
+	Delete(ctx context.Context, deckID uuid.UUID) error
+	// History returns at most limit events of the deck with sequence numbers greater than after, oldest first
+	History(ctx context.Context, deckID uuid.UUID, after int64, limit int) ([]DeckEvent, error)
 }
 
 var _ DeckProcessor = (*DeckRepository)(nil)
 
 type DeckRepository struct {
-	db *mongo.Collection
+	db     *mongo.Collection
+	random RandomSource
 }
 
-func NewDeckRepository(client *mongo.Client) *DeckRepository {
+func NewDeckRepository(client *mongo.Client, random RandomSource) *DeckRepository {
 	return &DeckRepository{
-		db: client.Database("cards").Collection("decks"),
+		db:     client.Database("cards").Collection("decks"),
+		random: random,
 	}
 }
 
-func (d *DeckRepository) Create(ctx context.Context, cardsCodes []string, shuffled bool) (Deck, error) {
-	deck, err := NewDeck(cardsCodes, shuffled)
+// deckDocument is a deck stored together with its MaxHistoryEvents latest events.
+// Events are written by the same update as the modification and removed together with the deck.
+type deckDocument struct {
+	Deck   `bson:",inline"`
+	Events []DeckEvent `bson:"events"`
+}
+
+// withoutEvents projects the deck document to the deck, the history is read only by History
+var withoutEvents = bson.D{{Key: "events", Value: 0}}
+
+func (d *DeckRepository) Create(ctx context.Context, options DeckOptions) (Deck, error) {
+	deck, err := NewDeck(options, d.random)
 	if err != nil {
 		return Deck{}, err
 	}
 
-	_, err = d.db.InsertOne(ctx, deck)
+	_, err = d.db.InsertOne(ctx, deckDocument{
+		Deck:   deck,
+		Events: []DeckEvent{newDeckEvent(ctx, deck, DeckEvent{Type: DeckEventCreated})},
+	})
 	if err != nil {
 		return Deck{}, err
 	}
@@ -43,33 +91,415 @@ func (d *DeckRepository) Create(ctx context.Context, cardsCodes []string, shuffl
 
 func (d *DeckRepository) Get(ctx context.Context, deckID uuid.UUID) (Deck, error) {
 	var deck Deck
-	err := d.db.FindOne(ctx, Deck{
-		ID: deckID,
-	}).Decode(&deck)
+	opts := options.FindOne().SetProjection(withoutEvents)
+	err := d.db.FindOne(ctx, bson.D{{Key: "_id", Value: deckID}}, opts).Decode(&deck)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
-			return Deck{}, pkg.NewNotFoundError(fmt.Sprintf("deck with ID %s not found", deckID))
+			return Deck{}, newDeckNotFoundError(deckID)
 		}
 		return Deck{}, err
 	}
+	if err := deck.checkNotExpired(now()); err != nil {
+		return Deck{}, err
+	}
 
 	return deck, nil
 }
 
-func (d *DeckRepository) DrawCards(ctx context.Context, deckID uuid.UUID, count int) ([]Card, error) {
+func (d *DeckRepository) Clone(ctx context.Context, deckID uuid.UUID) (Deck, error) {
 	deck, err := d.Get(ctx, deckID)
 	if err != nil {
-		return nil, err
+		return Deck{}, err
+	}
+
+	fork := deck.Fork()
+	_, err = d.db.InsertOne(ctx, deckDocument{
+		Deck:   fork,
+		Events: []DeckEvent{newDeckEvent(ctx, fork, DeckEvent{Type: DeckEventCloned})},
+	})
+	if err != nil {
+		return Deck{}, err
+	}
+	return fork, nil
+}
+
+// DrawCards removes cards matching the selector from the deck.
+// Draws of cards from the top are done by drawFromTop, other draws need the deck state and go through update.
+func (d *DeckRepository) DrawCards(ctx context.Context, deckID uuid.UUID, selector CardSelector) (Deck, []Card, error) {
+	if selector.Count < 0 {
+		return Deck{}, nil, newNegativeCountError()
+	}
+	if len(selector.Codes) == 0 && selector.Count > 0 && (selector.Position == PositionTop || selector.Position == "") {
+		return d.drawFromTop(ctx, deckID, selector.Count)
 	}
 
+	var cards []Card
+	deck, err := d.update(ctx, deckID, func(deck *Deck) (DeckEvent, error) {
+		var err error
+		cards, err = deck.Draw(selector, d.random)
+		return DeckEvent{Type: DeckEventDrawn, Cards: cards}, err
+	})
+	if err != nil {
+		return Deck{}, nil, err
+	}
+	return deck, cards, nil
+}
+
+// drawFromTop removes count cards from the top of the deck in a single atomic update.
+// The filter only matches decks holding at least count cards, so concurrent draws
+// (even from different server instances) can never hand out the same card.
+func (d *DeckRepository) drawFromTop(ctx context.Context, deckID uuid.UUID, count int) (Deck, []Card, error) {
+	updatedAt := now()
+	filter := bson.D{
+		{Key: "_id", Value: deckID},
+		{Key: "closed", Value: bson.D{{Key: "$ne", Value: true}}},
+		// same as Deck.checkClientSeeded, decks which are not provably fair do not have the field
+		{Key: "fairness.commitment", Value: bson.D{{Key: "$ne", Value: ""}}},
+		{Key: fmt.Sprintf("cards.%d", count-1), Value: bson.D{{Key: "$exists", Value: true}}},
+		notExpiredFilter(updatedAt),
+	}
+	if versions, ok := ifMatch(ctx); ok {
+		filter = append(filter, versionFilter(versions...))
+	}
+	// decks stored before versioning do not have the field, their version is 0
+	version := bson.D{{Key: "$add", Value: bson.A{bson.D{{Key: "$ifNull", Value: bson.A{"$version", 0}}}, 1}}}
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.D{
+			{Key: "cards", Value: bson.D{{Key: "$slice", Value: bson.A{"$cards", count, bson.D{{Key: "$size", Value: "$cards"}}}}}},
+			{Key: "version", Value: version},
+			{Key: "last_used_at", Value: updatedAt},
+			// same as newDeckEvent, fields of the stage see the deck before the draw
+			{Key: "events", Value: appendEvent(bson.D{
+				{Key: "deck_id", Value: deckID},
+				{Key: "seq", Value: version},
+				{Key: "type", Value: DeckEventDrawn},
+				{Key: "correlation_id", Value: pkg.GetCorrelationIDCtx(ctx)},
+				{Key: "at", Value: updatedAt},
+				{Key: "cards", Value: bson.D{{Key: "$slice", Value: bson.A{"$cards", count}}}},
+			})},
+			// same as Deck.rememberSnapshot, the snapshot holds the state before the draw
+			{Key: "snapshots", Value: bson.D{{Key: "$cond", Value: bson.D{
+				{Key: "if", Value: bson.D{{Key: "$and", Value: bson.A{
+					bson.D{{Key: "$gt", Value: bson.A{"$undo_depth", 0}}},
+					bson.D{{Key: "$ne", Value: bson.A{"$sealed", true}}},
+				}}}},
+				{Key: "then", Value: bson.D{{Key: "$slice", Value: bson.A{
+					bson.D{{Key: "$concatArrays", Value: bson.A{
+						bson.A{bson.D{
+							{Key: "cards", Value: "$cards"},
+							{Key: "piles", Value: "$piles"},
+							{Key: "shuffled", Value: "$shuffled"},
+							{Key: "seed", Value: "$seed"},
+						}},
+						bson.D{{Key: "$ifNull", Value: bson.A{"$snapshots", bson.A{}}}},
+					}}},
+					"$undo_depth",
+				}}}},
+				{Key: "else", Value: "$$REMOVE"},
+			}}}},
+			// same as Deck.touch, ttl is stored in nanoseconds and dates are added milliseconds
+			{Key: "expires_at", Value: bson.D{{Key: "$cond", Value: bson.D{
+				{Key: "if", Value: bson.D{{Key: "$gt", Value: bson.A{"$ttl", 0}}}},
+				{Key: "then", Value: bson.D{{Key: "$add", Value: bson.A{
+					updatedAt,
+					bson.D{{Key: "$toLong", Value: bson.D{{Key: "$divide", Value: bson.A{"$ttl", int64(time.Millisecond)}}}}},
+				}}}},
+				{Key: "else", Value: "$expires_at"},
+			}}}},
+		}}},
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.Before).SetProjection(withoutEvents)
+
+	var deck Deck
+	err := d.db.FindOneAndUpdate(ctx, filter, update, opts).Decode(&deck)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			// the deck either does not exist, expired, does not match If-Match, is closed, is not seeded
+			// or does not have enough cards
+			deck, err := d.Get(ctx, deckID)
+			if err != nil {
+				return Deck{}, nil, err
+			}
+			if err := deck.checkIfMatch(ctx); err != nil {
+				return Deck{}, nil, err
+			}
+			if err := deck.checkNotClosed(); err != nil {
+				return Deck{}, nil, err
+			}
+			if err := deck.checkClientSeeded(); err != nil {
+				return Deck{}, nil, err
+			}
+			return Deck{}, nil, newNotEnoughCardsError()
+		}
+		return Deck{}, nil, err
+	}
+
+	// deck holds the state before the update, the drawn cards are on its top
+	snapshot := deck.snapshot()
 	cards, err := deck.DrawCards(count)
 	if err != nil {
-		return nil, err
+		return Deck{}, nil, err
+	}
+	deck.rememberSnapshot(snapshot, DeckEvent{Type: DeckEventDrawn})
+	deck.touch(updatedAt)
+	deck.Version++
+	return deck, cards, nil
+}
+
+func (d *DeckRepository) ReturnCards(ctx context.Context, deckID uuid.UUID, codes []string, position string) (Deck, error) {
+	return d.update(ctx, deckID, func(deck *Deck) (DeckEvent, error) {
+		cards, err := deck.cardsToReturn(codes)
+		if err != nil {
+			return DeckEvent{}, err
+		}
+		return DeckEvent{Type: DeckEventReturned, Cards: cards}, deck.ReturnCards(codes, position, d.random)
+	})
+}
+
+func (d *DeckRepository) Shuffle(ctx context.Context, deckID uuid.UUID, options ShuffleOptions) (Deck, error) {
+	return d.update(ctx, deckID, func(deck *Deck) (DeckEvent, error) {
+		return DeckEvent{Type: DeckEventShuffled}, deck.Shuffle(options, d.random)
+	})
+}
+
+func (d *DeckRepository) DrawToPile(ctx context.Context, deckID uuid.UUID, pile string, count int) (Deck, error) {
+	return d.update(ctx, deckID, func(deck *Deck) (DeckEvent, error) {
+		if err := deck.DrawToPile(pile, count); err != nil {
+			return DeckEvent{}, err
+		}
+		return DeckEvent{Type: DeckEventDrawnToPile, Cards: deck.Piles[pile][:count], Piles: []string{pile}}, nil
+	})
+}
+
+func (d *DeckRepository) Deal(ctx context.Context, deckID uuid.UUID, hands []string, count int) (Deck, [][]Card, error) {
+	var dealt [][]Card
+	deck, err := d.update(ctx, deckID, func(deck *Deck) (DeckEvent, error) {
+		var err error
+		dealt, err = deck.Deal(hands, count)
+		return DeckEvent{Type: DeckEventDealt, Cards: slices.Concat(dealt...), Piles: hands}, err
+	})
+	if err != nil {
+		return Deck{}, nil, err
+	}
+	return deck, dealt, nil
+}
+
+func (d *DeckRepository) DrawFromPile(ctx context.Context, deckID uuid.UUID, pile string, selector CardSelector) (Deck, []Card, error) {
+	var cards []Card
+	deck, err := d.update(ctx, deckID, func(deck *Deck) (DeckEvent, error) {
+		var err error
+		cards, err = deck.DrawFromPile(pile, selector, d.random)
+		return DeckEvent{Type: DeckEventDrawnFromPile, Cards: cards, Piles: []string{pile}}, err
+	})
+	if err != nil {
+		return Deck{}, nil, err
+	}
+	return deck, cards, nil
+}
+
+func (d *DeckRepository) ShufflePile(ctx context.Context, deckID uuid.UUID, pile string) (Deck, error) {
+	return d.update(ctx, deckID, func(deck *Deck) (DeckEvent, error) {
+		return DeckEvent{Type: DeckEventPileShuffled, Piles: []string{pile}}, deck.ShufflePile(pile, d.random)
+	})
+}
+
+func (d *DeckRepository) Cut(ctx context.Context, deckID uuid.UUID, options CutOptions) (Deck, int, error) {
+	var position int
+	deck, err := d.update(ctx, deckID, func(deck *Deck) (DeckEvent, error) {
+		var err error
+		position, err = deck.Cut(options, d.random)
+		return DeckEvent{Type: DeckEventCut, Position: position}, err
+	})
+	if err != nil {
+		return Deck{}, 0, err
+	}
+	return deck, position, nil
+}
+
+func (d *DeckRepository) Undo(ctx context.Context, deckID uuid.UUID) (Deck, error) {
+	return d.update(ctx, deckID, func(deck *Deck) (DeckEvent, error) {
+		return DeckEvent{Type: DeckEventUndone}, deck.Undo()
+	})
+}
+
+func (d *DeckRepository) SeedFairness(ctx context.Context, deckID uuid.UUID, clientSeed string) (Deck, error) {
+	return d.update(ctx, deckID, func(deck *Deck) (DeckEvent, error) {
+		return DeckEvent{Type: DeckEventSeeded}, deck.SeedFairly(clientSeed)
+	})
+}
+
+func (d *DeckRepository) Close(ctx context.Context, deckID uuid.UUID) (Deck, error) {
+	return d.update(ctx, deckID, func(deck *Deck) (DeckEvent, error) {
+		deck.Close()
+		return DeckEvent{Type: DeckEventClosed}, nil
+	})
+}
+
+// Delete removes the deck unless it expired, expired decks are left to the TTL index
+func (d *DeckRepository) Delete(ctx context.Context, deckID uuid.UUID) error {
+	filter := bson.D{
+		{Key: "_id", Value: deckID},
+		notExpiredFilter(now()),
+	}
+	if versions, ok := ifMatch(ctx); ok {
+		filter = append(filter, versionFilter(versions...))
+	}
+	result, err := d.db.DeleteOne(ctx, filter)
+	if err != nil {
+		return err
 	}
+	if result.DeletedCount == 0 {
+		// the deck either does not exist, expired or does not match If-Match
+		deck, err := d.Get(ctx, deckID)
+		if err != nil {
+			return err
+		}
+		if err := deck.checkIfMatch(ctx); err != nil {
+			return err
+		}
+		return newDeckNotFoundError(deckID)
+	}
+	return nil
+}
 
-	_, err = d.db.ReplaceOne(ctx, Deck{ID: deck.ID}, deck)
+// History reads the page of events from the deck document, events are stored in the order of their sequence numbers
+func (d *DeckRepository) History(ctx context.Context, deckID uuid.UUID, after int64, limit int) ([]DeckEvent, error) {
+	projection := bson.D{
+		{Key: "expires_at", Value: 1},
+		{Key: "events", Value: bson.D{{Key: "$slice", Value: bson.A{
+			bson.D{{Key: "$filter", Value: bson.D{
+				{Key: "input", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$events", bson.A{}}}}},
+				{Key: "cond", Value: bson.D{{Key: "$gt", Value: bson.A{"$$this.seq", after}}}},
+			}}},
+			limit,
+		}}}},
+	}
+	var document deckDocument
+	opts := options.FindOne().SetProjection(projection)
+	err := d.db.FindOne(ctx, bson.D{{Key: "_id", Value: deckID}}, opts).Decode(&document)
 	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, newDeckNotFoundError(deckID)
+		}
 		return nil, err
 	}
-	return cards, nil
+	if err := document.checkNotExpired(now()); err != nil {
+		return nil, err
+	}
+	if document.Events == nil {
+		return []DeckEvent{}, nil
+	}
+	return document.Events, nil
+}
+
+// CreateIndexes creates the TTL index removing expired decks together with their history.
+// MongoDB removes expired documents in the background once a minute, Get reports decks expired in the meantime as gone.
+func (d *DeckRepository) CreateIndexes(ctx context.Context) error {
+	_, err := d.db.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	return err
+}
+
+// maxUpdateAttempts limits how many times update re-applies a modification losing races with concurrent ones
+const maxUpdateAttempts = 10
+
+// updateBackoff is the delay before the second attempt of update, every next attempt waits one more updateBackoff
+const updateBackoff = 5 * time.Millisecond
+
+// update applies fn to the current state of the deck and stores the result,
+// closed decks and decks not matching If-Match of the context are never updated.
+// The replacement is guarded by the deck version, when another modification wins the race
+// the state is loaded again and fn is re-applied, so concurrent modifications are never lost.
+// ConflictError is returned when the modification loses maxUpdateAttempts races.
+// The event returned by fn is appended to the history by the same replacement, the modification can be undone unless it is an undo.
+func (d *DeckRepository) update(ctx context.Context, deckID uuid.UUID, fn func(deck *Deck) (DeckEvent, error)) (Deck, error) {
+	for attempt := range maxUpdateAttempts {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return Deck{}, ctx.Err()
+			case <-time.After(time.Duration(attempt) * updateBackoff):
+			}
+		}
+
+		deck, err := d.Get(ctx, deckID)
+		if err != nil {
+			return Deck{}, err
+		}
+
+		// a version changed by a concurrent modification does not match If-Match after reload
+		if err := deck.checkIfMatch(ctx); err != nil {
+			return Deck{}, err
+		}
+		if err := deck.checkNotClosed(); err != nil {
+			return Deck{}, err
+		}
+		version := deck.Version
+		snapshot := deck.snapshot()
+		seeded := deck.checkClientSeeded()
+		event, err := fn(&deck)
+		if err != nil {
+			return Deck{}, err
+		}
+		if seeded != nil && event.requiresClientSeed() {
+			return Deck{}, seeded
+		}
+		deck.rememberSnapshot(snapshot, event)
+		deck.touch(now())
+		deck.Version = version + 1
+
+		filter := bson.D{
+			{Key: "_id", Value: deckID},
+			versionFilter(version),
+		}
+		// the deck is replaced without dropping its history, literals keep values starting with $ from being evaluated
+		update := mongo.Pipeline{
+			{{Key: "$replaceWith", Value: bson.D{{Key: "$mergeObjects", Value: bson.A{
+				bson.D{{Key: "$literal", Value: deck}},
+				bson.D{{Key: "events", Value: appendEvent(bson.D{{Key: "$literal", Value: newDeckEvent(ctx, deck, event)}})}},
+			}}}}},
+		}
+		result, err := d.db.UpdateOne(ctx, filter, update)
+		if err != nil {
+			return Deck{}, err
+		}
+		if result.MatchedCount == 1 {
+			return deck, nil
+		}
+	}
+	return Deck{}, pkg.NewConflictError(fmt.Sprintf("deck with ID %s is modified concurrently, try again later", deckID))
+}
+
+// appendEvent is the expression appending the event to the history of the deck document,
+// only MaxHistoryEvents latest events are kept
+func appendEvent(event bson.D) bson.D {
+	return bson.D{{Key: "$slice", Value: bson.A{
+		bson.D{{Key: "$concatArrays", Value: bson.A{
+			bson.D{{Key: "$ifNull", Value: bson.A{"$events", bson.A{}}}},
+			bson.A{event},
+		}}},
+		-MaxHistoryEvents,
+	}}}
+}
+
+// notExpiredFilter matches decks which did not expire before at, same as Deck.checkNotExpired
+func notExpiredFilter(at time.Time) bson.E {
+	return bson.E{Key: "$or", Value: bson.A{
+		bson.D{{Key: "expires_at", Value: bson.D{{Key: "$exists", Value: false}}}},
+		bson.D{{Key: "expires_at", Value: bson.D{{Key: "$gt", Value: at}}}},
+	}}
+}
+
+// versionFilter matches decks with any of the versions, decks stored before versioning match version 0
+func versionFilter(versions ...int64) bson.E {
+	values := bson.A{}
+	for _, version := range versions {
+		values = append(values, version)
+		if version == 0 {
+			values = append(values, nil)
+		}
+	}
+	return bson.E{Key: "version", Value: bson.D{{Key: "$in", Value: values}}}
 }
@@ -0,0 +1,41 @@
+package internal_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/prathoss/cards/internal"
+	"github.com/prathoss/cards/internal/decktest"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestMemoryDeckProcessor(t *testing.T) {
+	decktest.TestDeckProcessor(t, func(t *testing.T) internal.DeckProcessor {
+		return internal.NewMemoryDeckProcessor(internal.CryptoRandomSource{})
+	})
+}
+
+// TestDeckRepository runs only when CARDS_TEST_MONGO_CONN_STR points to a MongoDB instance
+func TestDeckRepository(t *testing.T) {
+	connection := os.Getenv("CARDS_TEST_MONGO_CONN_STR")
+	if connection == "" {
+		t.Skip("CARDS_TEST_MONGO_CONN_STR is not set")
+	}
+
+	ctx, cFunc := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cFunc()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(connection))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = client.Disconnect(context.Background())
+	})
+
+	decktest.TestDeckProcessor(t, func(t *testing.T) internal.DeckProcessor {
+		return internal.NewDeckRepository(client, internal.CryptoRandomSource{})
+	})
+}
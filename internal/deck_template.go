@@ -0,0 +1,94 @@
+package internal
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/prathoss/cards/pkg"
+)
+
+const (
+	DeckTypeStandard = "standard"
+	DeckTypePiquet   = "piquet"
+	DeckTypeSkat     = "skat"
+	DeckTypeEuchre   = "euchre"
+	DeckTypePinochle = "pinochle"
+	DeckTypeSpanish  = "spanish"
+)
+
+// DeckTemplate describes the cards of a single deck of a given type
+type DeckTemplate struct {
+	Name   string
+	Suits  []string
+	Values []string
+	// Copies is the number of times every card is present in a single deck, zero means once
+	Copies int
+}
+
+var frenchSuits = []string{CardSuitClubs, CardSuitDiamonds, CardSuitHearths, CardSuitSpades}
+
+// deckTemplates is the registry of supported deck types, indexed by their name
+var deckTemplates = map[string]DeckTemplate{
+	DeckTypeStandard: {
+		Name:   DeckTypeStandard,
+		Suits:  frenchSuits,
+		Values: []string{CardValueAce, CardValueTwo, CardValueThree, CardValueFour, CardValueFive, CardValueSix, CardValueSeven, CardValueEight, CardValueNine, CardValueTen, CardValueJack, CardValueQueen, CardValueKing},
+	},
+	DeckTypePiquet: {
+		Name:   DeckTypePiquet,
+		Suits:  frenchSuits,
+		Values: []string{CardValueAce, CardValueSeven, CardValueEight, CardValueNine, CardValueTen, CardValueJack, CardValueQueen, CardValueKing},
+	},
+	// Skat is played with the same 32 cards as Piquet
+	DeckTypeSkat: {
+		Name:   DeckTypeSkat,
+		Suits:  frenchSuits,
+		Values: []string{CardValueAce, CardValueSeven, CardValueEight, CardValueNine, CardValueTen, CardValueJack, CardValueQueen, CardValueKing},
+	},
+	DeckTypeEuchre: {
+		Name:   DeckTypeEuchre,
+		Suits:  frenchSuits,
+		Values: []string{CardValueAce, CardValueNine, CardValueTen, CardValueJack, CardValueQueen, CardValueKing},
+	},
+	DeckTypePinochle: {
+		Name:   DeckTypePinochle,
+		Suits:  frenchSuits,
+		Values: []string{CardValueAce, CardValueNine, CardValueTen, CardValueJack, CardValueQueen, CardValueKing},
+		Copies: 2,
+	},
+	DeckTypeSpanish: {
+		Name:   DeckTypeSpanish,
+		Suits:  []string{CardSuitCoins, CardSuitCups, CardSuitSwords, CardSuitBatons},
+		Values: []string{CardValueAce, CardValueTwo, CardValueThree, CardValueFour, CardValueFive, CardValueSix, CardValueSeven, CardValueSota, CardValueCaballo, CardValueRey},
+	},
+}
+
+// LookupDeckTemplate returns the template registered under the name, empty name is the standard deck
+func LookupDeckTemplate(name string) (DeckTemplate, bool) {
+	if name == "" {
+		name = DeckTypeStandard
+	}
+	template, ok := deckTemplates[name]
+	return template, ok
+}
+
+// DeckTypes returns names of all registered deck templates in alphabetical order
+func DeckTypes() []string {
+	names := make([]string, 0, len(deckTemplates))
+	for name := range deckTemplates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func newUnknownDeckTypeError(name string) *pkg.BadRequestError {
+	return pkg.NewBadRequestError(pkg.InvalidParam{
+		Name:   "type",
+		Reason: unknownDeckTypeReason(name),
+	})
+}
+
+func unknownDeckTypeReason(name string) string {
+	return fmt.Sprintf("unknown deck type %q, supported types are %v", name, DeckTypes())
+}
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/google/uuid"
+	"github.com/prathoss/cards/pkg"
 )
 
 func TestCard_Code(t *testing.T) {
@@ -29,6 +30,22 @@ func TestCard_Code(t *testing.T) {
 			},
 			want: "AH",
 		},
+		{
+			name: "RedJoker",
+			card: Card{
+				Value: CardValueJoker,
+				Suit:  CardSuitRed,
+			},
+			want: "XR",
+		},
+		{
+			name: "BlackJoker",
+			card: Card{
+				Value: CardValueJoker,
+				Suit:  CardSuitBlack,
+			},
+			want: "XB",
+		},
 	}
 
 	for _, tt := range tests {
@@ -40,19 +57,12 @@ func TestCard_Code(t *testing.T) {
 	}
 }
 
-func cardsToCodes(cards []Card) []string {
-	resultCodes := make([]string, 0, len(cards))
-	for _, c := range cards {
-		resultCodes = append(resultCodes, c.Code())
-	}
-	return resultCodes
-}
-
 func TestGenerateCards(t *testing.T) {
 	tests := []struct {
-		name  string
-		codes []string
-		want  []string
+		name   string
+		codes  []string
+		jokers bool
+		want   []string
 	}{
 		{
 			name:  "generate with multiple codes",
@@ -67,13 +77,25 @@ func TestGenerateCards(t *testing.T) {
 		{
 			name:  "generate with empty code slice",
 			codes: []string{},
-			want:  cardsToCodes(generateAllCardsCombinations()),
+			want:  cardsToCodes(generateAllCardsCombinations(deckTemplates[DeckTypeStandard])),
+		},
+		{
+			name:   "generate full deck with jokers",
+			codes:  []string{},
+			jokers: true,
+			want:   append(cardsToCodes(generateAllCardsCombinations(deckTemplates[DeckTypeStandard])), "XR", "XB"),
+		},
+		{
+			name:   "generate with joker codes",
+			codes:  []string{"XB", "AS"},
+			jokers: false,
+			want:   []string{"XB", "AS"},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := generateCards(tt.codes)
+			result := generateCards(deckTemplates[DeckTypeStandard], tt.codes, tt.jokers)
 			resultCodes := cardsToCodes(result)
 			if !slices.Equal(resultCodes, tt.want) {
 				t.Fatalf("got %v, want %v", resultCodes, tt.want)
@@ -83,6 +105,125 @@ func TestGenerateCards(t *testing.T) {
 	}
 }
 
+func TestNewDeck_MultipleDecks(t *testing.T) {
+	tests := []struct {
+		name    string
+		options DeckOptions
+		want    []string
+	}{
+		{
+			name:    "zero decks means single deck",
+			options: DeckOptions{Cards: []string{"AS", "KH"}},
+			want:    []string{"AS", "KH"},
+		},
+		{
+			name:    "filtered cards are repeated for every deck",
+			options: DeckOptions{Cards: []string{"AS", "KH"}, Decks: 3},
+			want:    []string{"AS", "KH", "AS", "KH", "AS", "KH"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deck, err := NewDeck(tt.options, CryptoRandomSource{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := cardsToCodes(deck.Cards); !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewDeck_SixDeckShoe(t *testing.T) {
+	deck, err := NewDeck(DeckOptions{Shuffled: true, Decks: 6}, CryptoRandomSource{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deck.Decks != 6 {
+		t.Errorf("unexpected deck count, got: %d, want: 6", deck.Decks)
+	}
+	if len(deck.Cards) != 6*52 {
+		t.Fatalf("unexpected card count, got: %d, want: %d", len(deck.Cards), 6*52)
+	}
+	counts := make(map[string]int)
+	for _, c := range deck.Cards {
+		counts[c.Code()]++
+	}
+	for code, count := range counts {
+		if count != 6 {
+			t.Errorf("card %s is in the shoe %d times, want 6", code, count)
+		}
+	}
+}
+
+func TestNewDeck_DeckTypes(t *testing.T) {
+	tests := []struct {
+		deckType  string
+		wantCards int
+		wantType  string
+	}{
+		{deckType: "", wantCards: 52, wantType: DeckTypeStandard},
+		{deckType: DeckTypeStandard, wantCards: 52, wantType: DeckTypeStandard},
+		{deckType: DeckTypePiquet, wantCards: 32, wantType: DeckTypePiquet},
+		{deckType: DeckTypeSkat, wantCards: 32, wantType: DeckTypeSkat},
+		{deckType: DeckTypeEuchre, wantCards: 24, wantType: DeckTypeEuchre},
+		{deckType: DeckTypePinochle, wantCards: 48, wantType: DeckTypePinochle},
+		{deckType: DeckTypeSpanish, wantCards: 40, wantType: DeckTypeSpanish},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.wantType, func(t *testing.T) {
+			deck, err := NewDeck(DeckOptions{Type: tt.deckType}, CryptoRandomSource{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if deck.Type != tt.wantType {
+				t.Errorf("unexpected deck type, got: %s, want: %s", deck.Type, tt.wantType)
+			}
+			if len(deck.Cards) != tt.wantCards {
+				t.Errorf("unexpected card count, got: %d, want: %d", len(deck.Cards), tt.wantCards)
+			}
+		})
+	}
+}
+
+func TestNewDeck_UnknownDeckType(t *testing.T) {
+	if _, err := NewDeck(DeckOptions{Type: "tarot"}, CryptoRandomSource{}); err == nil {
+		t.Errorf("expected error for unknown deck type")
+	}
+}
+
+func TestGenerateCards_Pinochle(t *testing.T) {
+	template := deckTemplates[DeckTypePinochle]
+
+	counts := make(map[string]int)
+	for _, c := range generateAllCardsCombinations(template) {
+		counts[c.Code()]++
+	}
+	if len(counts) != 24 {
+		t.Errorf("unexpected number of distinct cards, got: %d, want: 24", len(counts))
+	}
+	for code, count := range counts {
+		if count != 2 {
+			t.Errorf("card %s is in the deck %d times, want 2", code, count)
+		}
+	}
+}
+
+func TestGenerateAllCardsCombinationsByCode_UniqueCodes(t *testing.T) {
+	for _, name := range DeckTypes() {
+		t.Run(name, func(t *testing.T) {
+			template := deckTemplates[name]
+			distinct := len(template.Suits)*len(template.Values) + len(generateJokers())
+			if got := len(generateAllCardsCombinationsByCode(template)); got != distinct {
+				t.Errorf("card codes are not unique, got %d codes for %d cards", got, distinct)
+			}
+		})
+	}
+}
+
 func TestShuffleCards(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -113,7 +254,8 @@ func TestShuffleCards(t *testing.T) {
 			deck := &Deck{Cards: tt.cards}
 			copyBeforeShuffle := make([]Card, len(tt.cards))
 			copy(copyBeforeShuffle, tt.cards)
-			if err := deck.ShuffleCards(); err != nil {
+			// swapping with the first card on every step always changes order of more than two cards
+			if err := deck.ShuffleCards(NewFixtureRandomSource(0)); err != nil {
 				t.Fatalf("could not shuffle deck %s", err.Error())
 			}
 
@@ -183,6 +325,18 @@ func TestDrawCards(t *testing.T) {
 			count:   1,
 			wantErr: true,
 		},
+		{
+			name: "Try to draw negative count of cards",
+			deck: Deck{
+				ID: uuid.New(),
+				Cards: []Card{
+					{},
+					{},
+				},
+			},
+			count:   -1,
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -200,3 +354,328 @@ func TestDrawCards(t *testing.T) {
 		})
 	}
 }
+
+func TestReturnCards(t *testing.T) {
+	tests := []struct {
+		name     string
+		deck     Deck
+		codes    []string
+		position string
+		want     []string
+		wantErr  bool
+	}{
+		{
+			name:     "Return to top",
+			deck:     Deck{Selection: []string{"AS", "KH", "2C"}, Cards: []Card{{Value: CardValueTwo, Suit: CardSuitClubs}}},
+			codes:    []string{"AS", "KH"},
+			position: PositionTop,
+			want:     []string{"AS", "KH", "2C"},
+		},
+		{
+			name:     "Return to bottom",
+			deck:     Deck{Selection: []string{"AS", "KH", "2C"}, Cards: []Card{{Value: CardValueTwo, Suit: CardSuitClubs}}},
+			codes:    []string{"AS", "KH"},
+			position: PositionBottom,
+			want:     []string{"2C", "AS", "KH"},
+		},
+		{
+			name:     "Return card of another multi-deck copy",
+			deck:     Deck{Selection: []string{"AS"}, Decks: 2, Cards: []Card{{Value: CardValueAce, Suit: CardSuitSpades}}},
+			codes:    []string{"AS"},
+			position: PositionBottom,
+			want:     []string{"AS", "AS"},
+		},
+		{
+			name:     "Card already in the deck",
+			deck:     Deck{Selection: []string{"AS", "KH"}, Cards: []Card{{Value: CardValueAce, Suit: CardSuitSpades}}},
+			codes:    []string{"AS"},
+			position: PositionTop,
+			wantErr:  true,
+		},
+		{
+			name:     "Same card returned twice",
+			deck:     Deck{Selection: []string{"AS", "KH"}},
+			codes:    []string{"AS", "AS"},
+			position: PositionTop,
+			wantErr:  true,
+		},
+		{
+			name:     "Card not in deck composition",
+			deck:     Deck{Selection: []string{"AS", "KH"}},
+			codes:    []string{"2C"},
+			position: PositionTop,
+			wantErr:  true,
+		},
+		{
+			name:     "Joker not in deck composition",
+			deck:     Deck{},
+			codes:    []string{"XR"},
+			position: PositionTop,
+			wantErr:  true,
+		},
+		{
+			name:     "Unknown position",
+			deck:     Deck{Selection: []string{"AS"}},
+			codes:    []string{"AS"},
+			position: "middle",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.deck.ReturnCards(tt.codes, tt.position, CryptoRandomSource{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Deck.ReturnCards() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := cardsToCodes(tt.deck.Cards); !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReturnCards_Random(t *testing.T) {
+	deck, err := NewDeck(DeckOptions{}, CryptoRandomSource{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := deck.DrawCards(10); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := deck.ReturnCards([]string{"AC", "2C", "3C"}, PositionRandom, CryptoRandomSource{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(deck.Cards) != 45 {
+		t.Fatalf("unexpected card count, got: %d, want: 45", len(deck.Cards))
+	}
+	codes := cardsToCodes(deck.Cards)
+	for _, code := range []string{"AC", "2C", "3C"} {
+		if !slices.Contains(codes, code) {
+			t.Errorf("returned card %s is not in the deck", code)
+		}
+	}
+}
+
+func TestReturnDrawnCards(t *testing.T) {
+	deck, err := NewDeck(DeckOptions{Cards: []string{"AS", "KH", "2C"}, Decks: 2}, CryptoRandomSource{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := deck.DrawCards(4); err != nil {
+		t.Fatal(err)
+	}
+
+	deck.ReturnDrawnCards()
+
+	if got, want := cardsToCodes(deck.Cards), []string{"KH", "2C", "AS", "AS", "KH", "2C"}; !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestShuffle(t *testing.T) {
+	tests := []struct {
+		name        string
+		returnDrawn bool
+		want        int
+	}{
+		{
+			name:        "Shuffle remaining cards",
+			returnDrawn: false,
+			want:        42,
+		},
+		{
+			name:        "Return drawn cards and shuffle",
+			returnDrawn: true,
+			want:        52,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deck, err := NewDeck(DeckOptions{}, CryptoRandomSource{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := deck.DrawCards(10); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := deck.Shuffle(ShuffleOptions{ReturnDrawn: tt.returnDrawn}, CryptoRandomSource{}); err != nil {
+				t.Fatal(err)
+			}
+			if !deck.Shuffled {
+				t.Errorf("deck is not marked as shuffled")
+			}
+			if len(deck.Cards) != tt.want {
+				t.Errorf("unexpected card count, got: %d, want: %d", len(deck.Cards), tt.want)
+			}
+		})
+	}
+}
+
+func TestDraw(t *testing.T) {
+	tests := []struct {
+		name     string
+		selector CardSelector
+		want     []string
+		wantDeck []string
+		wantErr  []pkg.InvalidParam
+	}{
+		{
+			name:     "Draw from top",
+			selector: CardSelector{Count: 1, Position: PositionTop},
+			want:     []string{"AS"},
+			wantDeck: []string{"KH", "2C", "3D"},
+		},
+		{
+			name:     "Draw from bottom",
+			selector: CardSelector{Count: 3, Position: PositionBottom},
+			want:     []string{"KH", "2C", "3D"},
+			wantDeck: []string{"AS"},
+		},
+		{
+			name:     "Draw specific cards",
+			selector: CardSelector{Codes: []string{"2C", "AS"}},
+			want:     []string{"2C", "AS"},
+			wantDeck: []string{"KH", "3D"},
+		},
+		{
+			name:     "Draw missing cards",
+			selector: CardSelector{Codes: []string{"2C", "QH", "2C"}},
+			wantErr: []pkg.InvalidParam{
+				{Name: "cards", Reason: "card QH is not in the deck"},
+				{Name: "cards", Reason: "card 2C is not in the deck"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deck, err := NewDeck(DeckOptions{Cards: []string{"AS", "KH", "2C", "3D"}}, CryptoRandomSource{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			cards, err := deck.Draw(tt.selector, CryptoRandomSource{})
+			if tt.wantErr != nil {
+				if want := pkg.NewBadRequestError(tt.wantErr...); err == nil || err.Error() != want.Error() {
+					t.Fatalf("Deck.Draw() error = %v, want %v", err, want)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := cardsToCodes(cards); !slices.Equal(got, tt.want) {
+				t.Errorf("drawn got %v, want %v", got, tt.want)
+			}
+			if got := cardsToCodes(deck.Cards); !slices.Equal(got, tt.wantDeck) {
+				t.Errorf("deck got %v, want %v", got, tt.wantDeck)
+			}
+		})
+	}
+}
+
+func TestDraw_Random(t *testing.T) {
+	deck, err := NewDeck(DeckOptions{}, CryptoRandomSource{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cards, err := deck.Draw(CardSelector{Count: 5, Position: PositionRandom}, CryptoRandomSource{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cards) != 5 || len(deck.Cards) != 47 {
+		t.Fatalf("unexpected card counts, drawn: %d, remaining: %d", len(cards), len(deck.Cards))
+	}
+	for _, c := range cards {
+		if slices.Contains(deck.Cards, c) {
+			t.Errorf("drawn card %s is still in the deck", c.Code())
+		}
+	}
+}
+
+func TestPeek(t *testing.T) {
+	tests := []struct {
+		name     string
+		count    int
+		position string
+		want     []string
+		wantErr  bool
+	}{
+		{
+			name:     "Peek at top",
+			count:    2,
+			position: PositionTop,
+			want:     []string{"AS", "KH"},
+		},
+		{
+			name:     "Peek at bottom",
+			count:    1,
+			position: PositionBottom,
+			want:     []string{"3D"},
+		},
+		{
+			name:     "Peek at random",
+			count:    1,
+			position: PositionRandom,
+			wantErr:  true,
+		},
+		{
+			name:     "Peek at more cards than remaining",
+			count:    5,
+			position: PositionTop,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deck, err := NewDeck(DeckOptions{Cards: []string{"AS", "KH", "2C", "3D"}}, CryptoRandomSource{})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			cards, err := deck.Peek(tt.count, tt.position)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Deck.Peek() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := cardsToCodes(cards); !tt.wantErr && !slices.Equal(got, tt.want) {
+				t.Errorf("peeked got %v, want %v", got, tt.want)
+			}
+			if got := cardsToCodes(deck.Cards); len(got) != 4 {
+				t.Errorf("Deck.Peek() changed the deck to %v", got)
+			}
+		})
+	}
+}
+
+func TestNewDeck_Seeded(t *testing.T) {
+	seed := uint64(2024)
+	first, err := NewDeck(DeckOptions{Shuffled: true, Seed: &seed}, CryptoRandomSource{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := NewDeck(DeckOptions{Shuffled: true, Seed: &seed}, CryptoRandomSource{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(cardsToCodes(first.Cards), cardsToCodes(second.Cards)) {
+		t.Errorf("decks with the same seed are shuffled differently")
+	}
+
+	otherSeed := uint64(2025)
+	other, err := NewDeck(DeckOptions{Shuffled: true, Seed: &otherSeed}, CryptoRandomSource{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slices.Equal(cardsToCodes(first.Cards), cardsToCodes(other.Cards)) {
+		t.Errorf("decks with different seeds are shuffled the same")
+	}
+}
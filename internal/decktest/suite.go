@@ -0,0 +1,1222 @@
+// Package decktest provides a conformance test suite for implementations of internal.DeckProcessor.
+package decktest
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prathoss/cards/internal"
+	"github.com/prathoss/cards/pkg"
+)
+
+// NewProcessorFunc returns the DeckProcessor under test.
+// It is called once for every test case of the suite.
+type NewProcessorFunc func(t *testing.T) internal.DeckProcessor
+
+// TestDeckProcessor runs the conformance test suite against DeckProcessor implementation.
+// Every implementation should pass it, so that the server behaves the same regardless of the storage backend.
+func TestDeckProcessor(t *testing.T, newProcessor NewProcessorFunc) {
+	t.Run("Create", func(t *testing.T) { testCreate(t, newProcessor(t)) })
+	t.Run("CreateShuffled", func(t *testing.T) { testCreateShuffled(t, newProcessor(t)) })
+	t.Run("CreateDeckType", func(t *testing.T) { testCreateDeckType(t, newProcessor(t)) })
+	t.Run("GetNotFound", func(t *testing.T) { testGetNotFound(t, newProcessor(t)) })
+	t.Run("DrawCards", func(t *testing.T) { testDrawCards(t, newProcessor(t)) })
+	t.Run("DrawCardsNotFound", func(t *testing.T) { testDrawCardsNotFound(t, newProcessor(t)) })
+	t.Run("DrawCardsNotEnoughCards", func(t *testing.T) { testDrawCardsNotEnoughCards(t, newProcessor(t)) })
+	t.Run("DrawCardsPositions", func(t *testing.T) { testDrawCardsPositions(t, newProcessor(t)) })
+	t.Run("DrawCardsByCode", func(t *testing.T) { testDrawCardsByCode(t, newProcessor(t)) })
+	t.Run("DrawCardsConcurrently", func(t *testing.T) { testDrawCardsConcurrently(t, newProcessor(t)) })
+	t.Run("ReturnCards", func(t *testing.T) { testReturnCards(t, newProcessor(t)) })
+	t.Run("ReturnCardsInvalid", func(t *testing.T) { testReturnCardsInvalid(t, newProcessor(t)) })
+	t.Run("ReturnCardsNotFound", func(t *testing.T) { testReturnCardsNotFound(t, newProcessor(t)) })
+	t.Run("Shuffle", func(t *testing.T) { testShuffle(t, newProcessor(t)) })
+	t.Run("SeededShuffle", func(t *testing.T) { testSeededShuffle(t, newProcessor(t)) })
+	t.Run("ShuffleMethod", func(t *testing.T) { testShuffleMethod(t, newProcessor(t)) })
+	t.Run("ShuffleNotFound", func(t *testing.T) { testShuffleNotFound(t, newProcessor(t)) })
+	t.Run("Deal", func(t *testing.T) { testDeal(t, newProcessor(t)) })
+	t.Run("Cut", func(t *testing.T) { testCut(t, newProcessor(t)) })
+	t.Run("Lifecycle", func(t *testing.T) { testLifecycle(t, newProcessor(t)) })
+	t.Run("Expiry", func(t *testing.T) { testExpiry(t, newProcessor(t)) })
+	t.Run("Delete", func(t *testing.T) { testDelete(t, newProcessor(t)) })
+	t.Run("DeleteExpired", func(t *testing.T) { testDeleteExpired(t, newProcessor(t)) })
+	t.Run("History", func(t *testing.T) { testHistory(t, newProcessor(t)) })
+	t.Run("HistoryCapped", func(t *testing.T) { testHistoryCapped(t, newProcessor(t)) })
+	t.Run("Undo", func(t *testing.T) { testUndo(t, newProcessor(t)) })
+	t.Run("UndoSealedDraw", func(t *testing.T) { testUndoSealedDraw(t, newProcessor(t)) })
+	t.Run("Clone", func(t *testing.T) { testClone(t, newProcessor(t)) })
+	t.Run("IfMatch", func(t *testing.T) { testIfMatch(t, newProcessor(t)) })
+	t.Run("Close", func(t *testing.T) { testClose(t, newProcessor(t)) })
+	t.Run("CreateSealed", func(t *testing.T) { testCreateSealed(t, newProcessor(t)) })
+	t.Run("CreateHidden", func(t *testing.T) { testCreateHidden(t, newProcessor(t)) })
+	t.Run("CreateFair", func(t *testing.T) { testCreateFair(t, newProcessor(t)) })
+	t.Run("Piles", func(t *testing.T) { testPiles(t, newProcessor(t)) })
+	t.Run("PileNotFound", func(t *testing.T) { testPileNotFound(t, newProcessor(t)) })
+}
+
+func testCreate(t *testing.T, processor internal.DeckProcessor) {
+	ctx := context.Background()
+	codes := []string{"AS", "KD", "10H", "2C"}
+
+	deck, err := processor.Create(ctx, internal.DeckOptions{Cards: codes})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if deck.ID == uuid.Nil {
+		t.Errorf("Create() returned deck without ID")
+	}
+	if got := cardsToCodes(deck.Cards); !slices.Equal(got, codes) {
+		t.Errorf("Create() cards = %v, want %v", got, codes)
+	}
+
+	stored, err := processor.Get(ctx, deck.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if stored.ID != deck.ID {
+		t.Errorf("Get() ID = %v, want %v", stored.ID, deck.ID)
+	}
+	if stored.Shuffled {
+		t.Errorf("Get() shuffled = true, want false")
+	}
+	if got := cardsToCodes(stored.Cards); !slices.Equal(got, codes) {
+		t.Errorf("Get() cards = %v, want %v", got, codes)
+	}
+}
+
+func testCreateShuffled(t *testing.T, processor internal.DeckProcessor) {
+	ctx := context.Background()
+
+	deck, err := processor.Create(ctx, internal.DeckOptions{Shuffled: true})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	stored, err := processor.Get(ctx, deck.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if !stored.Shuffled {
+		t.Errorf("Get() shuffled = false, want true")
+	}
+	if got, want := cardsToCodes(stored.Cards), cardsToCodes(deck.Cards); !slices.Equal(got, want) {
+		t.Errorf("Get() cards = %v, want order returned by Create() %v", got, want)
+	}
+}
+
+func testCreateDeckType(t *testing.T, processor internal.DeckProcessor) {
+	ctx := context.Background()
+
+	deck, err := processor.Create(ctx, internal.DeckOptions{Type: internal.DeckTypePiquet, Decks: 2})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	stored, err := processor.Get(ctx, deck.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if stored.Type != internal.DeckTypePiquet {
+		t.Errorf("Get() type = %s, want %s", stored.Type, internal.DeckTypePiquet)
+	}
+	if stored.Decks != 2 {
+		t.Errorf("Get() decks = %d, want 2", stored.Decks)
+	}
+	if len(stored.Cards) != 64 {
+		t.Errorf("Get() remaining = %d, want 64", len(stored.Cards))
+	}
+}
+
+func testGetNotFound(t *testing.T, processor internal.DeckProcessor) {
+	_, err := processor.Get(context.Background(), uuid.New())
+	assertNotFound(t, err)
+}
+
+func testDrawCards(t *testing.T, processor internal.DeckProcessor) {
+	ctx := context.Background()
+
+	deck, err := processor.Create(ctx, internal.DeckOptions{Cards: []string{"AS", "KD", "10H", "2C"}})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	_, cards, err := processor.DrawCards(ctx, deck.ID, internal.CardSelector{Count: 3})
+	if err != nil {
+		t.Fatalf("DrawCards() error = %v", err)
+	}
+	if got, want := cardsToCodes(cards), []string{"AS", "KD", "10H"}; !slices.Equal(got, want) {
+		t.Errorf("DrawCards() = %v, want %v", got, want)
+	}
+
+	stored, err := processor.Get(ctx, deck.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got, want := cardsToCodes(stored.Cards), []string{"2C"}; !slices.Equal(got, want) {
+		t.Errorf("remaining cards = %v, want %v", got, want)
+	}
+}
+
+func testDrawCardsPositions(t *testing.T, processor internal.DeckProcessor) {
+	ctx := context.Background()
+
+	deck, err := processor.Create(ctx, internal.DeckOptions{Cards: []string{"AS", "KD", "10H", "2C", "3S"}})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	_, cards, err := processor.DrawCards(ctx, deck.ID, internal.CardSelector{Count: 2, Position: internal.PositionBottom})
+	if err != nil {
+		t.Fatalf("DrawCards() error = %v", err)
+	}
+	if got, want := cardsToCodes(cards), []string{"2C", "3S"}; !slices.Equal(got, want) {
+		t.Errorf("DrawCards() = %v, want %v", got, want)
+	}
+
+	_, cards, err = processor.DrawCards(ctx, deck.ID, internal.CardSelector{Count: 2, Position: internal.PositionRandom})
+	if err != nil {
+		t.Fatalf("DrawCards() error = %v", err)
+	}
+	if len(cards) != 2 {
+		t.Fatalf("DrawCards() drew %d cards, want 2", len(cards))
+	}
+
+	stored, err := processor.Get(ctx, deck.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	got := append(cardsToCodes(stored.Cards), cardsToCodes(cards)...)
+	slices.Sort(got)
+	if want := []string{"10H", "AS", "KD"}; !slices.Equal(got, want) {
+		t.Errorf("remaining and randomly drawn cards = %v, want %v", got, want)
+	}
+}
+
+func testDrawCardsByCode(t *testing.T, processor internal.DeckProcessor) {
+	ctx := context.Background()
+
+	deck, err := processor.Create(ctx, internal.DeckOptions{Cards: []string{"AS", "KD", "10H", "2C"}})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	_, cards, err := processor.DrawCards(ctx, deck.ID, internal.CardSelector{Codes: []string{"10H", "AS"}})
+	if err != nil {
+		t.Fatalf("DrawCards() error = %v", err)
+	}
+	if got, want := cardsToCodes(cards), []string{"10H", "AS"}; !slices.Equal(got, want) {
+		t.Errorf("DrawCards() = %v, want %v", got, want)
+	}
+
+	_, _, err = processor.DrawCards(ctx, deck.ID, internal.CardSelector{Codes: []string{"KD", "AS"}})
+	assertBadRequest(t, err)
+
+	stored, err := processor.Get(ctx, deck.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got, want := cardsToCodes(stored.Cards), []string{"KD", "2C"}; !slices.Equal(got, want) {
+		t.Errorf("remaining cards = %v, want %v", got, want)
+	}
+}
+
+func testDrawCardsNotFound(t *testing.T, processor internal.DeckProcessor) {
+	_, _, err := processor.DrawCards(context.Background(), uuid.New(), internal.CardSelector{Count: 1})
+	assertNotFound(t, err)
+}
+
+func testDrawCardsNotEnoughCards(t *testing.T, processor internal.DeckProcessor) {
+	ctx := context.Background()
+
+	deck, err := processor.Create(ctx, internal.DeckOptions{Cards: []string{"AS", "KD"}})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	_, _, err = processor.DrawCards(ctx, deck.ID, internal.CardSelector{Count: 3})
+	assertBadRequest(t, err)
+	_, _, err = processor.DrawCards(ctx, deck.ID, internal.CardSelector{Count: -1})
+	assertBadRequest(t, err)
+
+	_, cards, err := processor.DrawCards(ctx, deck.ID, internal.CardSelector{Count: 0})
+	if err != nil {
+		t.Fatalf("DrawCards() of no cards error = %v", err)
+	}
+	if len(cards) != 0 {
+		t.Errorf("DrawCards() of no cards = %v", cards)
+	}
+
+	stored, err := processor.Get(ctx, deck.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if len(stored.Cards) != 2 {
+		t.Errorf("draws changed the deck, remaining = %d, want 2", len(stored.Cards))
+	}
+}
+
+func testDrawCardsConcurrently(t *testing.T, processor internal.DeckProcessor) {
+	ctx := context.Background()
+
+	deck, err := processor.Create(ctx, internal.DeckOptions{Shuffled: true})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	const drawers = 20
+	const cardsPerDraw = 3
+	var mu sync.Mutex
+	var drawn []string
+	var unexpectedErrs []error
+	wg := &sync.WaitGroup{}
+	for range drawers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, cards, err := processor.DrawCards(ctx, deck.ID, internal.CardSelector{Count: cardsPerDraw})
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				var badRequestError *pkg.BadRequestError
+				if !errors.As(err, &badRequestError) {
+					unexpectedErrs = append(unexpectedErrs, err)
+				}
+				return
+			}
+			drawn = append(drawn, cardsToCodes(cards)...)
+		}()
+	}
+	wg.Wait()
+
+	if len(unexpectedErrs) > 0 {
+		t.Fatalf("DrawCards() returned unexpected errors: %v", unexpectedErrs)
+	}
+	// the deck has 52 cards, so only 17 draws of 3 cards can succeed
+	if want := len(deck.Cards) / cardsPerDraw * cardsPerDraw; len(drawn) != want {
+		t.Errorf("drew %d cards, want %d", len(drawn), want)
+	}
+	seen := make(map[string]bool, len(drawn))
+	for _, code := range drawn {
+		if seen[code] {
+			t.Errorf("card %s was drawn more than once", code)
+		}
+		seen[code] = true
+	}
+}
+
+func testReturnCards(t *testing.T, processor internal.DeckProcessor) {
+	ctx := context.Background()
+
+	deck, err := processor.Create(ctx, internal.DeckOptions{Cards: []string{"AS", "KD", "10H", "2C"}})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, _, err := processor.DrawCards(ctx, deck.ID, internal.CardSelector{Count: 3}); err != nil {
+		t.Fatalf("DrawCards() error = %v", err)
+	}
+
+	returned, err := processor.ReturnCards(ctx, deck.ID, []string{"KD"}, internal.PositionTop)
+	if err != nil {
+		t.Fatalf("ReturnCards() error = %v", err)
+	}
+	if got, want := cardsToCodes(returned.Cards), []string{"KD", "2C"}; !slices.Equal(got, want) {
+		t.Errorf("ReturnCards() cards = %v, want %v", got, want)
+	}
+
+	if _, err := processor.ReturnCards(ctx, deck.ID, []string{"AS"}, internal.PositionBottom); err != nil {
+		t.Fatalf("ReturnCards() error = %v", err)
+	}
+	stored, err := processor.Get(ctx, deck.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got, want := cardsToCodes(stored.Cards), []string{"KD", "2C", "AS"}; !slices.Equal(got, want) {
+		t.Errorf("Get() cards = %v, want %v", got, want)
+	}
+}
+
+func testReturnCardsInvalid(t *testing.T, processor internal.DeckProcessor) {
+	ctx := context.Background()
+
+	deck, err := processor.Create(ctx, internal.DeckOptions{Cards: []string{"AS", "KD"}})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	_, err = processor.ReturnCards(ctx, deck.ID, []string{"AS"}, internal.PositionTop)
+	assertBadRequest(t, err)
+	_, err = processor.ReturnCards(ctx, deck.ID, []string{"QH"}, internal.PositionTop)
+	assertBadRequest(t, err)
+
+	stored, err := processor.Get(ctx, deck.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got, want := cardsToCodes(stored.Cards), []string{"AS", "KD"}; !slices.Equal(got, want) {
+		t.Errorf("failed return changed the deck, cards = %v, want %v", got, want)
+	}
+}
+
+func testReturnCardsNotFound(t *testing.T, processor internal.DeckProcessor) {
+	_, err := processor.ReturnCards(context.Background(), uuid.New(), []string{"AS"}, internal.PositionTop)
+	assertNotFound(t, err)
+}
+
+func testShuffle(t *testing.T, processor internal.DeckProcessor) {
+	ctx := context.Background()
+
+	deck, err := processor.Create(ctx, internal.DeckOptions{})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, _, err := processor.DrawCards(ctx, deck.ID, internal.CardSelector{Count: 2}); err != nil {
+		t.Fatalf("DrawCards() error = %v", err)
+	}
+
+	shuffled, err := processor.Shuffle(ctx, deck.ID, internal.ShuffleOptions{})
+	if err != nil {
+		t.Fatalf("Shuffle() error = %v", err)
+	}
+	if !shuffled.Shuffled || len(shuffled.Cards) != 50 {
+		t.Errorf("Shuffle() shuffled = %v, remaining = %d, want true and 50", shuffled.Shuffled, len(shuffled.Cards))
+	}
+
+	if _, err := processor.Shuffle(ctx, deck.ID, internal.ShuffleOptions{ReturnDrawn: true}); err != nil {
+		t.Fatalf("Shuffle() error = %v", err)
+	}
+	stored, err := processor.Get(ctx, deck.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if !stored.Shuffled {
+		t.Errorf("Get() shuffled = false, want true")
+	}
+	got := cardsToCodes(stored.Cards)
+	want := cardsToCodes(deck.Cards)
+	slices.Sort(got)
+	slices.Sort(want)
+	if !slices.Equal(got, want) {
+		t.Errorf("Get() cards = %v, want all cards of the deck %v", got, want)
+	}
+}
+
+func testShuffleMethod(t *testing.T, processor internal.DeckProcessor) {
+	ctx := context.Background()
+
+	unshuffled, err := processor.Create(ctx, internal.DeckOptions{})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	// eight out faros restore the order of a 52 card deck
+	deck, err := processor.Create(ctx, internal.DeckOptions{
+		Shuffled:      true,
+		ShuffleMethod: internal.ShuffleMethod{Mode: internal.ShuffleModeFaroOut, Passes: 8},
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if got, want := cardsToCodes(deck.Cards), cardsToCodes(unshuffled.Cards); !slices.Equal(got, want) {
+		t.Errorf("Create() cards = %v, want %v", got, want)
+	}
+
+	shuffled, err := processor.Shuffle(ctx, deck.ID, internal.ShuffleOptions{
+		Method: internal.ShuffleMethod{Mode: internal.ShuffleModeFaroIn},
+	})
+	if err != nil {
+		t.Fatalf("Shuffle() error = %v", err)
+	}
+	stored, err := processor.Get(ctx, deck.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got, want := cardsToCodes(stored.Cards), cardsToCodes(shuffled.Cards); !slices.Equal(got, want) {
+		t.Errorf("Get() cards = %v, want %v", got, want)
+	}
+	// an in faro moves the top card to the second position
+	if stored.Cards[1] != deck.Cards[0] {
+		t.Errorf("Get() second card = %v, want %v", stored.Cards[1], deck.Cards[0])
+	}
+
+	_, err = processor.Shuffle(ctx, deck.ID, internal.ShuffleOptions{Method: internal.ShuffleMethod{Mode: "unknown"}})
+	assertBadRequest(t, err)
+}
+
+func testSeededShuffle(t *testing.T, processor internal.DeckProcessor) {
+	ctx := context.Background()
+	seed := uint64(42)
+
+	first, err := processor.Create(ctx, internal.DeckOptions{Shuffled: true, Seed: &seed})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	second, err := processor.Create(ctx, internal.DeckOptions{Shuffled: true, Seed: &seed})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if got, want := cardsToCodes(second.Cards), cardsToCodes(first.Cards); !slices.Equal(got, want) {
+		t.Errorf("decks created with the same seed differ, got %v, want %v", got, want)
+	}
+
+	stored, err := processor.Get(ctx, first.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if stored.Seed == nil || *stored.Seed != seed {
+		t.Errorf("Get() seed = %v, want %d", stored.Seed, seed)
+	}
+
+	reseed := uint64(7)
+	firstShuffled, err := processor.Shuffle(ctx, first.ID, internal.ShuffleOptions{ReturnDrawn: true, Seed: &reseed})
+	if err != nil {
+		t.Fatalf("Shuffle() error = %v", err)
+	}
+	secondShuffled, err := processor.Shuffle(ctx, second.ID, internal.ShuffleOptions{ReturnDrawn: true, Seed: &reseed})
+	if err != nil {
+		t.Fatalf("Shuffle() error = %v", err)
+	}
+	if got, want := cardsToCodes(secondShuffled.Cards), cardsToCodes(firstShuffled.Cards); !slices.Equal(got, want) {
+		t.Errorf("decks reshuffled with the same seed differ, got %v, want %v", got, want)
+	}
+}
+
+func testShuffleNotFound(t *testing.T, processor internal.DeckProcessor) {
+	_, err := processor.Shuffle(context.Background(), uuid.New(), internal.ShuffleOptions{})
+	assertNotFound(t, err)
+}
+
+func testDeal(t *testing.T, processor internal.DeckProcessor) {
+	ctx := context.Background()
+
+	deck, err := processor.Create(ctx, internal.DeckOptions{})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	updated, hands, err := processor.Deal(ctx, deck.ID, []string{"north", "south"}, 3)
+	if err != nil {
+		t.Fatalf("Deal() error = %v", err)
+	}
+	if len(updated.Cards) != 46 {
+		t.Errorf("Deal() remaining = %d, want 46", len(updated.Cards))
+	}
+	wantNorth := cardsToCodes([]internal.Card{deck.Cards[0], deck.Cards[2], deck.Cards[4]})
+	wantSouth := cardsToCodes([]internal.Card{deck.Cards[1], deck.Cards[3], deck.Cards[5]})
+	if got := cardsToCodes(hands[0]); !slices.Equal(got, wantNorth) {
+		t.Errorf("Deal() north = %v, want %v", got, wantNorth)
+	}
+	if got := cardsToCodes(hands[1]); !slices.Equal(got, wantSouth) {
+		t.Errorf("Deal() south = %v, want %v", got, wantSouth)
+	}
+
+	stored, err := processor.Get(ctx, deck.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got := cardsToCodes(stored.Piles["north"]); !slices.Equal(got, wantNorth) {
+		t.Errorf("Get() north pile = %v, want %v", got, wantNorth)
+	}
+	if got := cardsToCodes(stored.Piles["south"]); !slices.Equal(got, wantSouth) {
+		t.Errorf("Get() south pile = %v, want %v", got, wantSouth)
+	}
+
+	_, _, err = processor.Deal(ctx, deck.ID, []string{"north", "south"}, 24)
+	assertBadRequest(t, err)
+	stored, err = processor.Get(ctx, deck.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if len(stored.Cards) != 46 {
+		t.Errorf("failed Deal() changed remaining to %d, want 46", len(stored.Cards))
+	}
+
+	_, _, err = processor.Deal(ctx, uuid.New(), []string{"north"}, 1)
+	assertNotFound(t, err)
+}
+
+func testCut(t *testing.T, processor internal.DeckProcessor) {
+	ctx := context.Background()
+
+	deck, err := processor.Create(ctx, internal.DeckOptions{})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	cut, position, err := processor.Cut(ctx, deck.ID, internal.CutOptions{Position: 10})
+	if err != nil {
+		t.Fatalf("Cut() error = %v", err)
+	}
+	want := cardsToCodes(append(slices.Clone(deck.Cards[10:]), deck.Cards[:10]...))
+	if got := cardsToCodes(cut.Cards); position != 10 || !slices.Equal(got, want) {
+		t.Errorf("Cut() position = %d, cards = %v, want 10 and %v", position, got, want)
+	}
+
+	_, position, err = processor.Cut(ctx, deck.ID, internal.CutOptions{Min: 20, Max: 30})
+	if err != nil {
+		t.Fatalf("Cut() error = %v", err)
+	}
+	if position < 20 || position > 30 {
+		t.Errorf("Cut() position = %d, want between 20 and 30", position)
+	}
+	stored, err := processor.Get(ctx, deck.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	want = cardsToCodes(append(slices.Clone(cut.Cards[position:]), cut.Cards[:position]...))
+	if got := cardsToCodes(stored.Cards); !slices.Equal(got, want) {
+		t.Errorf("Get() cards = %v, want %v", got, want)
+	}
+
+	_, _, err = processor.Cut(ctx, deck.ID, internal.CutOptions{Position: 52})
+	assertBadRequest(t, err)
+
+	_, _, err = processor.Cut(ctx, uuid.New(), internal.CutOptions{})
+	assertNotFound(t, err)
+}
+
+func testLifecycle(t *testing.T, processor internal.DeckProcessor) {
+	ctx := context.Background()
+
+	deck, err := processor.Create(ctx, internal.DeckOptions{TTL: time.Hour})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if deck.CreatedAt.IsZero() || !deck.LastUsedAt.Equal(deck.CreatedAt) || deck.ExpiresAt == nil || !deck.ExpiresAt.Equal(deck.CreatedAt.Add(time.Hour)) {
+		t.Fatalf("Create() created at = %v, last used at = %v, expires at = %v", deck.CreatedAt, deck.LastUsedAt, deck.ExpiresAt)
+	}
+
+	// top draws and other modifications take different paths in some backends
+	for _, selector := range []internal.CardSelector{{Count: 1}, {Count: 1, Position: internal.PositionBottom}} {
+		previous, err := processor.Get(ctx, deck.ID)
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		time.Sleep(2 * time.Millisecond)
+		if _, _, err := processor.DrawCards(ctx, deck.ID, selector); err != nil {
+			t.Fatalf("DrawCards() error = %v", err)
+		}
+
+		stored, err := processor.Get(ctx, deck.ID)
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		if !stored.CreatedAt.Equal(deck.CreatedAt) {
+			t.Errorf("Get() created at = %v, want %v", stored.CreatedAt, deck.CreatedAt)
+		}
+		if !stored.LastUsedAt.After(previous.LastUsedAt) {
+			t.Errorf("Get() last used at = %v, want after %v", stored.LastUsedAt, previous.LastUsedAt)
+		}
+		if stored.ExpiresAt == nil || !stored.ExpiresAt.Equal(stored.LastUsedAt.Add(time.Hour)) {
+			t.Errorf("Get() expires at = %v, want an hour after %v", stored.ExpiresAt, stored.LastUsedAt)
+		}
+	}
+}
+
+func testExpiry(t *testing.T, processor internal.DeckProcessor) {
+	ctx := context.Background()
+
+	deck, err := processor.Create(ctx, internal.DeckOptions{TTL: time.Millisecond})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	var goneError *pkg.GoneError
+	if _, err := processor.Get(ctx, deck.ID); !errors.As(err, &goneError) {
+		t.Errorf("Get() error = %v, want GoneError", err)
+	}
+	if _, _, err := processor.DrawCards(ctx, deck.ID, internal.CardSelector{Count: 1}); !errors.As(err, &goneError) {
+		t.Errorf("DrawCards() error = %v, want GoneError", err)
+	}
+	if _, err := processor.Shuffle(ctx, deck.ID, internal.ShuffleOptions{}); !errors.As(err, &goneError) {
+		t.Errorf("Shuffle() error = %v, want GoneError", err)
+	}
+	if _, err := processor.History(ctx, deck.ID, 0, internal.DefaultHistoryLimit); !errors.As(err, &goneError) {
+		t.Errorf("History() error = %v, want GoneError", err)
+	}
+}
+
+func testDeleteExpired(t *testing.T, processor internal.DeckProcessor) {
+	ctx := context.Background()
+
+	deck, err := processor.Create(ctx, internal.DeckOptions{TTL: time.Millisecond})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	var goneError *pkg.GoneError
+	if err := processor.Delete(ctx, deck.ID); !errors.As(err, &goneError) {
+		t.Errorf("Delete() error = %v, want GoneError", err)
+	}
+	if err := processor.Delete(internal.WithIfMatch(ctx, []int64{deck.Version}), deck.ID); !errors.As(err, &goneError) {
+		t.Errorf("Delete() with If-Match error = %v, want GoneError", err)
+	}
+}
+
+func testDelete(t *testing.T, processor internal.DeckProcessor) {
+	ctx := context.Background()
+
+	deck, err := processor.Create(ctx, internal.DeckOptions{})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	if err := processor.Delete(ctx, deck.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	_, err = processor.Get(ctx, deck.ID)
+	assertNotFound(t, err)
+	_, err = processor.History(ctx, deck.ID, 0, internal.DefaultHistoryLimit)
+	assertNotFound(t, err)
+
+	err = processor.Delete(ctx, deck.ID)
+	assertNotFound(t, err)
+}
+
+func testHistoryCapped(t *testing.T, processor internal.DeckProcessor) {
+	ctx := context.Background()
+
+	deck, err := processor.Create(ctx, internal.DeckOptions{})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	for range internal.MaxHistoryEvents {
+		if _, err := processor.Shuffle(ctx, deck.ID, internal.ShuffleOptions{}); err != nil {
+			t.Fatalf("Shuffle() error = %v", err)
+		}
+	}
+
+	// the created event is the oldest one and it is dropped
+	events, err := processor.History(ctx, deck.ID, 0, 1)
+	if err != nil {
+		t.Fatalf("History() error = %v", err)
+	}
+	if len(events) != 1 || events[0].Seq != 2 {
+		t.Errorf("History() oldest event = %v, want sequence number 2", events)
+	}
+	events, err = processor.History(ctx, deck.ID, internal.MaxHistoryEvents, internal.MaxHistoryLimit)
+	if err != nil {
+		t.Fatalf("History() error = %v", err)
+	}
+	if len(events) != 1 || events[0].Seq != internal.MaxHistoryEvents+1 {
+		t.Errorf("History() latest events = %v, want only sequence number %d", events, internal.MaxHistoryEvents+1)
+	}
+}
+
+func testHistory(t *testing.T, processor internal.DeckProcessor) {
+	correlationID := uuid.New()
+	ctx := pkg.SetCorrelationID(context.Background(), correlationID)
+
+	deck, err := processor.Create(ctx, internal.DeckOptions{Cards: []string{"AS", "KD", "10H", "2C"}})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, _, err := processor.DrawCards(ctx, deck.ID, internal.CardSelector{Count: 2}); err != nil {
+		t.Fatalf("DrawCards() error = %v", err)
+	}
+	if _, _, err := processor.DrawCards(ctx, deck.ID, internal.CardSelector{Count: 1, Position: internal.PositionBottom}); err != nil {
+		t.Fatalf("DrawCards() error = %v", err)
+	}
+	if _, err := processor.ReturnCards(ctx, deck.ID, []string{"KD"}, internal.PositionTop); err != nil {
+		t.Fatalf("ReturnCards() error = %v", err)
+	}
+	if _, err := processor.Shuffle(ctx, deck.ID, internal.ShuffleOptions{}); err != nil {
+		t.Fatalf("Shuffle() error = %v", err)
+	}
+	// failed modifications are not recorded
+	_, _, err = processor.DrawCards(ctx, deck.ID, internal.CardSelector{Count: 5})
+	assertBadRequest(t, err)
+
+	events, err := processor.History(ctx, deck.ID, 0, internal.MaxHistoryLimit)
+	if err != nil {
+		t.Fatalf("History() error = %v", err)
+	}
+	want := []struct {
+		typ   string
+		cards []string
+	}{
+		{typ: internal.DeckEventCreated},
+		{typ: internal.DeckEventDrawn, cards: []string{"AS", "KD"}},
+		{typ: internal.DeckEventDrawn, cards: []string{"2C"}},
+		{typ: internal.DeckEventReturned, cards: []string{"KD"}},
+		{typ: internal.DeckEventShuffled},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("History() returned %d events, want %d", len(events), len(want))
+	}
+	for i, event := range events {
+		if event.Seq != int64(i+1) || event.Type != want[i].typ || !slices.Equal(cardsToCodes(event.Cards), want[i].cards) {
+			t.Errorf("History()[%d] = %d %s %v, want %d %s %v",
+				i, event.Seq, event.Type, cardsToCodes(event.Cards), i+1, want[i].typ, want[i].cards)
+		}
+		if event.DeckID != deck.ID || event.CorrelationID != correlationID || event.At.IsZero() {
+			t.Errorf("History()[%d] deck = %s, correlation = %s, at = %v", i, event.DeckID, event.CorrelationID, event.At)
+		}
+	}
+
+	page, err := processor.History(ctx, deck.ID, 2, 2)
+	if err != nil {
+		t.Fatalf("History() error = %v", err)
+	}
+	if len(page) != 2 || page[0].Seq != 3 || page[1].Seq != 4 {
+		t.Errorf("History() page after 2 = %v, want events 3 and 4", page)
+	}
+
+	_, err = processor.History(ctx, uuid.New(), 0, 1)
+	assertNotFound(t, err)
+
+	if err := processor.Delete(ctx, deck.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	_, err = processor.History(ctx, deck.ID, 0, 1)
+	assertNotFound(t, err)
+}
+
+func testUndoSealedDraw(t *testing.T, processor internal.DeckProcessor) {
+	ctx := context.Background()
+
+	deck, err := processor.Create(ctx, internal.DeckOptions{Shuffled: true, Sealed: true, UndoDepth: 3})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, err := processor.Shuffle(ctx, deck.ID, internal.ShuffleOptions{}); err != nil {
+		t.Fatalf("Shuffle() error = %v", err)
+	}
+
+	// top and bottom draws are stored by different updates
+	if _, _, err := processor.DrawCards(ctx, deck.ID, internal.CardSelector{Count: 2}); err != nil {
+		t.Fatalf("DrawCards() error = %v", err)
+	}
+	_, err = processor.Undo(ctx, deck.ID)
+	assertConflict(t, err)
+
+	if _, err := processor.Shuffle(ctx, deck.ID, internal.ShuffleOptions{}); err != nil {
+		t.Fatalf("Shuffle() error = %v", err)
+	}
+	if _, _, err := processor.DrawCards(ctx, deck.ID, internal.CardSelector{Count: 2, Position: internal.PositionBottom}); err != nil {
+		t.Fatalf("DrawCards() error = %v", err)
+	}
+	_, err = processor.Undo(ctx, deck.ID)
+	assertConflict(t, err)
+}
+
+func testUndo(t *testing.T, processor internal.DeckProcessor) {
+	ctx := context.Background()
+
+	deck, err := processor.Create(ctx, internal.DeckOptions{Shuffled: true, UndoDepth: 3})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	initial := cardsToCodes(deck.Cards)
+
+	if _, err := processor.Shuffle(ctx, deck.ID, internal.ShuffleOptions{}); err != nil {
+		t.Fatalf("Shuffle() error = %v", err)
+	}
+	shuffled, err := processor.Get(ctx, deck.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if _, _, err := processor.DrawCards(ctx, deck.ID, internal.CardSelector{Count: 2}); err != nil {
+		t.Fatalf("DrawCards() error = %v", err)
+	}
+	if _, _, err := processor.DrawCards(ctx, deck.ID, internal.CardSelector{Count: 1, Position: internal.PositionBottom}); err != nil {
+		t.Fatalf("DrawCards() error = %v", err)
+	}
+
+	// every undo restores the exact order before the modification, the draw from the top included
+	wants := [][]string{
+		cardsToCodes(shuffled.Cards[2:]),
+		cardsToCodes(shuffled.Cards),
+		initial,
+	}
+	for i, want := range wants {
+		undone, err := processor.Undo(ctx, deck.ID)
+		if err != nil {
+			t.Fatalf("Undo() #%d error = %v", i+1, err)
+		}
+		if got := cardsToCodes(undone.Cards); !slices.Equal(got, want) {
+			t.Errorf("Undo() #%d cards = %v, want %v", i+1, got, want)
+		}
+	}
+	_, err = processor.Undo(ctx, deck.ID)
+	assertConflict(t, err)
+
+	// the depth limits how many modifications can be undone
+	if _, _, err := processor.DrawCards(ctx, deck.ID, internal.CardSelector{Count: 1}); err != nil {
+		t.Fatalf("DrawCards() error = %v", err)
+	}
+	for range 4 {
+		if _, _, err := processor.DrawCards(ctx, deck.ID, internal.CardSelector{Count: 1, Position: internal.PositionBottom}); err != nil {
+			t.Fatalf("DrawCards() error = %v", err)
+		}
+	}
+	for i := range 3 {
+		if _, err := processor.Undo(ctx, deck.ID); err != nil {
+			t.Fatalf("Undo() #%d error = %v", i+1, err)
+		}
+	}
+	_, err = processor.Undo(ctx, deck.ID)
+	assertConflict(t, err)
+
+	withoutUndo, err := processor.Create(ctx, internal.DeckOptions{})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, _, err := processor.DrawCards(ctx, withoutUndo.ID, internal.CardSelector{Count: 1}); err != nil {
+		t.Fatalf("DrawCards() error = %v", err)
+	}
+	_, err = processor.Undo(ctx, withoutUndo.ID)
+	assertConflict(t, err)
+
+	_, err = processor.Undo(ctx, uuid.New())
+	assertNotFound(t, err)
+}
+
+func testClone(t *testing.T, processor internal.DeckProcessor) {
+	ctx := context.Background()
+
+	deck, err := processor.Create(ctx, internal.DeckOptions{Shuffled: true})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, _, err := processor.DrawCards(ctx, deck.ID, internal.CardSelector{Count: 2}); err != nil {
+		t.Fatalf("DrawCards() error = %v", err)
+	}
+
+	clone, err := processor.Clone(ctx, deck.ID)
+	if err != nil {
+		t.Fatalf("Clone() error = %v", err)
+	}
+	if clone.ID == deck.ID || clone.ParentID == nil || *clone.ParentID != deck.ID {
+		t.Errorf("Clone() id = %s, parent = %v, want new id and parent %s", clone.ID, clone.ParentID, deck.ID)
+	}
+	if got, want := cardsToCodes(clone.Cards), cardsToCodes(deck.Cards[2:]); !slices.Equal(got, want) {
+		t.Errorf("Clone() cards = %v, want %v", got, want)
+	}
+
+	// the decks are modified independently
+	if _, _, err := processor.DrawCards(ctx, clone.ID, internal.CardSelector{Count: 10}); err != nil {
+		t.Fatalf("DrawCards() error = %v", err)
+	}
+	stored, err := processor.Get(ctx, deck.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if len(stored.Cards) != 50 {
+		t.Errorf("remaining cards of the original deck = %d, want 50", len(stored.Cards))
+	}
+	storedClone, err := processor.Get(ctx, clone.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if len(storedClone.Cards) != 40 || storedClone.ParentID == nil || *storedClone.ParentID != deck.ID {
+		t.Errorf("stored clone remaining = %d, parent = %v, want 40 and %s", len(storedClone.Cards), storedClone.ParentID, deck.ID)
+	}
+
+	events, err := processor.History(ctx, clone.ID, 0, internal.MaxHistoryLimit)
+	if err != nil {
+		t.Fatalf("History() error = %v", err)
+	}
+	if len(events) != 2 || events[0].Type != internal.DeckEventCloned {
+		t.Errorf("History() of the clone = %v, want cloned and drawn events", events)
+	}
+
+	_, err = processor.Clone(ctx, uuid.New())
+	assertNotFound(t, err)
+}
+
+func testIfMatch(t *testing.T, processor internal.DeckProcessor) {
+	ctx := context.Background()
+
+	deck, err := processor.Create(ctx, internal.DeckOptions{})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	stale := internal.WithIfMatch(ctx, []int64{deck.Version})
+
+	drawn, _, err := processor.DrawCards(stale, deck.ID, internal.CardSelector{Count: 1})
+	if err != nil {
+		t.Fatalf("DrawCards() error = %v", err)
+	}
+	if drawn.Version <= deck.Version {
+		t.Errorf("DrawCards() version = %d, want greater than %d", drawn.Version, deck.Version)
+	}
+	stored, err := processor.Get(ctx, deck.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if stored.Version != drawn.Version || len(stored.Cards) != len(drawn.Cards) {
+		t.Errorf("DrawCards() version = %d, remaining = %d, stored %d and %d",
+			drawn.Version, len(drawn.Cards), stored.Version, len(stored.Cards))
+	}
+
+	// both draws from the top and other modifications respect the precondition
+	_, _, err = processor.DrawCards(stale, deck.ID, internal.CardSelector{Count: 1})
+	assertPreconditionFailed(t, err)
+	_, _, err = processor.DrawCards(stale, deck.ID, internal.CardSelector{Count: 1, Position: internal.PositionBottom})
+	assertPreconditionFailed(t, err)
+	_, err = processor.Shuffle(stale, deck.ID, internal.ShuffleOptions{})
+	assertPreconditionFailed(t, err)
+	err = processor.Delete(stale, deck.ID)
+	assertPreconditionFailed(t, err)
+
+	current := internal.WithIfMatch(ctx, []int64{drawn.Version})
+	if _, err := processor.Shuffle(current, deck.ID, internal.ShuffleOptions{}); err != nil {
+		t.Fatalf("Shuffle() error = %v", err)
+	}
+	stored, err = processor.Get(ctx, deck.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if len(stored.Cards) != 51 {
+		t.Errorf("remaining cards = %d, want 51", len(stored.Cards))
+	}
+	if err := processor.Delete(internal.WithIfMatch(ctx, []int64{stored.Version}), deck.ID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	err = processor.Delete(current, deck.ID)
+	assertNotFound(t, err)
+}
+
+func testClose(t *testing.T, processor internal.DeckProcessor) {
+	ctx := context.Background()
+
+	deck, err := processor.Create(ctx, internal.DeckOptions{})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	closed, err := processor.Close(ctx, deck.ID)
+	if err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if !closed.Closed {
+		t.Errorf("Close() closed = false, want true")
+	}
+
+	_, _, err = processor.DrawCards(ctx, deck.ID, internal.CardSelector{Count: 1})
+	assertConflict(t, err)
+	_, _, err = processor.DrawCards(ctx, deck.ID, internal.CardSelector{Count: 1, Position: internal.PositionBottom})
+	assertConflict(t, err)
+	_, err = processor.Shuffle(ctx, deck.ID, internal.ShuffleOptions{})
+	assertConflict(t, err)
+
+	stored, err := processor.Get(ctx, deck.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if !stored.Closed || len(stored.Cards) != 52 {
+		t.Errorf("Get() closed = %v, remaining = %d, want true and 52", stored.Closed, len(stored.Cards))
+	}
+
+	_, err = processor.Close(ctx, uuid.New())
+	assertNotFound(t, err)
+}
+
+func testCreateSealed(t *testing.T, processor internal.DeckProcessor) {
+	ctx := context.Background()
+
+	deck, err := processor.Create(ctx, internal.DeckOptions{Sealed: true, MaxPeek: 3})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	stored, err := processor.Get(ctx, deck.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if !stored.Sealed {
+		t.Errorf("Get() sealed = false, want true")
+	}
+	if stored.MaxPeek != 3 {
+		t.Errorf("Get() max peek = %d, want 3", stored.MaxPeek)
+	}
+	var forbiddenError *pkg.ForbiddenError
+	if err := stored.CheckCanOpen(); !errors.As(err, &forbiddenError) {
+		t.Errorf("CheckCanOpen() error = %v, want ForbiddenError", err)
+	}
+}
+
+func testCreateHidden(t *testing.T, processor internal.DeckProcessor) {
+	ctx := context.Background()
+
+	deck, err := processor.Create(ctx, internal.DeckOptions{Hidden: true, OwnerToken: "owner"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	stored, err := processor.Get(ctx, deck.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if !stored.Hidden {
+		t.Errorf("Get() hidden = false, want true")
+	}
+	if !stored.IsOwner("owner") || stored.IsOwner("other") {
+		t.Errorf("Get() deck does not recognize its owner token")
+	}
+}
+
+func testCreateFair(t *testing.T, processor internal.DeckProcessor) {
+	ctx := context.Background()
+
+	created, err := processor.Create(ctx, internal.DeckOptions{Fair: true})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if created.Fairness == nil || created.Fairness.ServerSeedHash == "" || created.Fairness.Commitment != "" {
+		t.Fatalf("Create() fairness = %+v, want server seed hash only", created.Fairness)
+	}
+
+	// the deck can not be used until the client seed is sent
+	_, _, err = processor.DrawCards(ctx, created.ID, internal.CardSelector{Count: 1})
+	assertConflict(t, err)
+	_, _, err = processor.DrawCards(ctx, created.ID, internal.CardSelector{Count: 1, Position: internal.PositionBottom})
+	assertConflict(t, err)
+	_, err = processor.Shuffle(ctx, created.ID, internal.ShuffleOptions{})
+	assertConflict(t, err)
+
+	deck, err := processor.SeedFairness(ctx, created.ID, "client")
+	if err != nil {
+		t.Fatalf("SeedFairness() error = %v", err)
+	}
+	if deck.Fairness.ServerSeedHash != created.Fairness.ServerSeedHash || deck.Fairness.Commitment == "" || !deck.Shuffled {
+		t.Errorf("SeedFairness() fairness = %+v, shuffled = %v", deck.Fairness, deck.Shuffled)
+	}
+	_, err = processor.SeedFairness(ctx, created.ID, "again")
+	assertConflict(t, err)
+
+	if _, _, err := processor.DrawCards(ctx, deck.ID, internal.CardSelector{Count: 52}); err != nil {
+		t.Fatalf("DrawCards() error = %v", err)
+	}
+
+	stored, err := processor.Get(ctx, deck.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if stored.Fairness == nil || *stored.Fairness != *deck.Fairness {
+		t.Fatalf("Get() fairness = %+v, want %+v", stored.Fairness, deck.Fairness)
+	}
+	verification, err := stored.VerifyFairness()
+	if err != nil {
+		t.Fatalf("VerifyFairness() error = %v", err)
+	}
+	if !verification.Valid {
+		t.Errorf("VerifyFairness() valid = false, want true")
+	}
+}
+
+func testPiles(t *testing.T, processor internal.DeckProcessor) {
+	ctx := context.Background()
+
+	deck, err := processor.Create(ctx, internal.DeckOptions{Cards: []string{"AS", "KD", "10H", "2C", "3S"}})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	withPile, err := processor.DrawToPile(ctx, deck.ID, "hand", 4)
+	if err != nil {
+		t.Fatalf("DrawToPile() error = %v", err)
+	}
+	if got, want := cardsToCodes(withPile.Piles["hand"]), []string{"AS", "KD", "10H", "2C"}; !slices.Equal(got, want) {
+		t.Errorf("DrawToPile() pile = %v, want %v", got, want)
+	}
+
+	_, cards, err := processor.DrawFromPile(ctx, deck.ID, "hand", internal.CardSelector{Codes: []string{"10H"}})
+	if err != nil {
+		t.Fatalf("DrawFromPile() error = %v", err)
+	}
+	if got, want := cardsToCodes(cards), []string{"10H"}; !slices.Equal(got, want) {
+		t.Errorf("DrawFromPile() = %v, want %v", got, want)
+	}
+	_, cards, err = processor.DrawFromPile(ctx, deck.ID, "hand", internal.CardSelector{Count: 1, Position: internal.PositionBottom})
+	if err != nil {
+		t.Fatalf("DrawFromPile() error = %v", err)
+	}
+	if got, want := cardsToCodes(cards), []string{"2C"}; !slices.Equal(got, want) {
+		t.Errorf("DrawFromPile() = %v, want %v", got, want)
+	}
+	_, _, err = processor.DrawFromPile(ctx, deck.ID, "hand", internal.CardSelector{Codes: []string{"3S"}})
+	assertBadRequest(t, err)
+
+	if _, err := processor.ShufflePile(ctx, deck.ID, "hand"); err != nil {
+		t.Fatalf("ShufflePile() error = %v", err)
+	}
+
+	stored, err := processor.Get(ctx, deck.ID)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	got, err := stored.Pile("hand")
+	if err != nil {
+		t.Fatalf("Pile() error = %v", err)
+	}
+	gotCodes := cardsToCodes(got)
+	slices.Sort(gotCodes)
+	if want := []string{"AS", "KD"}; !slices.Equal(gotCodes, want) {
+		t.Errorf("Get() pile = %v, want %v", gotCodes, want)
+	}
+	if got, want := cardsToCodes(stored.Cards), []string{"3S"}; !slices.Equal(got, want) {
+		t.Errorf("Get() cards = %v, want %v", got, want)
+	}
+}
+
+func testPileNotFound(t *testing.T, processor internal.DeckProcessor) {
+	ctx := context.Background()
+
+	deck, err := processor.Create(ctx, internal.DeckOptions{})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	_, _, err = processor.DrawFromPile(ctx, deck.ID, "missing", internal.CardSelector{Count: 1})
+	assertNotFound(t, err)
+	_, err = processor.ShufflePile(ctx, deck.ID, "missing")
+	assertNotFound(t, err)
+	_, err = processor.DrawToPile(ctx, uuid.New(), "hand", 1)
+	assertNotFound(t, err)
+}
+
+func assertNotFound(t *testing.T, err error) {
+	t.Helper()
+	var notFoundError *pkg.NotFoundError
+	if !errors.As(err, &notFoundError) {
+		t.Errorf("expected NotFoundError, got %v", err)
+	}
+}
+
+func assertPreconditionFailed(t *testing.T, err error) {
+	t.Helper()
+	var preconditionFailedError *pkg.PreconditionFailedError
+	if !errors.As(err, &preconditionFailedError) {
+		t.Errorf("expected PreconditionFailedError, got %v", err)
+	}
+}
+
+func assertConflict(t *testing.T, err error) {
+	t.Helper()
+	var conflictError *pkg.ConflictError
+	if !errors.As(err, &conflictError) {
+		t.Errorf("expected ConflictError, got %v", err)
+	}
+}
+
+func assertBadRequest(t *testing.T, err error) {
+	t.Helper()
+	var badRequestError *pkg.BadRequestError
+	if !errors.As(err, &badRequestError) {
+		t.Errorf("expected BadRequestError, got %v", err)
+	}
+}
+
+func cardsToCodes(cards []internal.Card) []string {
+	codes := make([]string, 0, len(cards))
+	for _, c := range cards {
+		codes = append(codes, c.Code())
+	}
+	return codes
+}
@@ -0,0 +1,61 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/prathoss/cards/pkg"
+)
+
+type ifMatchKeyType string
+
+const ifMatchKey ifMatchKeyType = "if-match"
+
+// DeckETag returns the strong entity tag of the current version of the deck
+func DeckETag(deck Deck) string {
+	return strconv.Quote(strconv.FormatInt(deck.Version, 10))
+}
+
+// ParseIfMatch returns versions of the deck listed by the If-Match header.
+// It reports false when the header is missing or matches any version, entity tags of other versions never match.
+func ParseIfMatch(header string) ([]int64, bool) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return nil, false
+	}
+	versions := []int64{}
+	for _, tag := range strings.Split(header, ",") {
+		// weak entity tags never match, If-Match uses the strong comparison
+		unquoted, err := strconv.Unquote(strings.TrimSpace(tag))
+		if err != nil {
+			continue
+		}
+		if version, err := strconv.ParseInt(unquoted, 10, 64); err == nil {
+			versions = append(versions, version)
+		}
+	}
+	return versions, true
+}
+
+// WithIfMatch restricts modifications of decks done with the context to the versions
+func WithIfMatch(ctx context.Context, versions []int64) context.Context {
+	return context.WithValue(ctx, ifMatchKey, versions)
+}
+
+// ifMatch returns versions the context restricts modifications to, see WithIfMatch
+func ifMatch(ctx context.Context) ([]int64, bool) {
+	versions, ok := ctx.Value(ifMatchKey).([]int64)
+	return versions, ok
+}
+
+// checkIfMatch returns PreconditionFailedError when the context restricts modifications to other versions of the deck
+func (d *Deck) checkIfMatch(ctx context.Context) error {
+	versions, ok := ifMatch(ctx)
+	if ok && !slices.Contains(versions, d.Version) {
+		return pkg.NewPreconditionFailedError(fmt.Sprintf("deck with ID %s was modified, its current entity tag is %s", d.ID, DeckETag(*d)))
+	}
+	return nil
+}
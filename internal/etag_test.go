@@ -0,0 +1,65 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/prathoss/cards/pkg"
+)
+
+func TestParseIfMatch(t *testing.T) {
+	tests := []struct {
+		name         string
+		header       string
+		wantVersions []int64
+		wantOK       bool
+	}{
+		{name: "Missing", header: "", wantOK: false},
+		{name: "Any", header: "*", wantOK: false},
+		{name: "Single", header: `"3"`, wantVersions: []int64{3}, wantOK: true},
+		{name: "List", header: `"3", "5"`, wantVersions: []int64{3, 5}, wantOK: true},
+		{name: "Weak", header: `W/"3"`, wantVersions: []int64{}, wantOK: true},
+		{name: "Unquoted", header: `3`, wantVersions: []int64{}, wantOK: true},
+		{name: "OtherTag", header: `"abc", "4"`, wantVersions: []int64{4}, wantOK: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotVersions, gotOK := ParseIfMatch(tt.header)
+			if gotOK != tt.wantOK || !slices.Equal(gotVersions, tt.wantVersions) {
+				t.Errorf("ParseIfMatch() = %v, %v, want %v, %v", gotVersions, gotOK, tt.wantVersions, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestDeck_checkIfMatch(t *testing.T) {
+	deck := Deck{Version: 3}
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		wantFail bool
+	}{
+		{name: "NoPrecondition", ctx: context.Background(), wantFail: false},
+		{name: "CurrentVersion", ctx: WithIfMatch(context.Background(), []int64{2, 3}), wantFail: false},
+		{name: "StaleVersion", ctx: WithIfMatch(context.Background(), []int64{2}), wantFail: true},
+		{name: "NoVersion", ctx: WithIfMatch(context.Background(), []int64{}), wantFail: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := deck.checkIfMatch(tt.ctx)
+			var preconditionFailedError *pkg.PreconditionFailedError
+			if errors.As(err, &preconditionFailedError) != tt.wantFail {
+				t.Errorf("Deck.checkIfMatch() error = %v, wantFail %v", err, tt.wantFail)
+			}
+		})
+	}
+
+	if got := DeckETag(deck); got != `"3"` {
+		t.Errorf("DeckETag() = %s, want %q", got, `"3"`)
+	}
+}
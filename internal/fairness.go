@@ -0,0 +1,204 @@
+package internal
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/prathoss/cards/pkg"
+)
+
+// serverSeedSize is the number of random bytes in the server seed of a provably fair deck
+const serverSeedSize = 32
+
+// Fairness holds the commit-reveal data of a provably fair deck.
+// The hash of the server seed is published on creation, before the client seed is known, so the server can not pick
+// a server seed favourable for the client seed. The initial order is derived from both seeds once the client seed
+// is sent, see fairShuffle, and the commitment to it is published right away.
+// The server seed is revealed once the deck is exhausted or closed.
+type Fairness struct {
+	ServerSeed     string `json:"-" bson:"server_seed"`
+	ServerSeedHash string `json:"server_seed_hash" bson:"server_seed_hash"`
+	ClientSeed     string `json:"client_seed" bson:"client_seed"`
+	// Commitment is empty until the deck is shuffled with the client seed
+	Commitment string `json:"commitment" bson:"commitment"`
+}
+
+// newFairness generates a secret server seed, it is taken from crypto/rand regardless of the configured
+// RandomSource, because players must not be able to predict it
+func newFairness() (Fairness, error) {
+	serverSeed := make([]byte, serverSeedSize)
+	if _, err := rand.Read(serverSeed); err != nil {
+		return Fairness{}, err
+	}
+	encoded := hex.EncodeToString(serverSeed)
+	return Fairness{
+		ServerSeed:     encoded,
+		ServerSeedHash: serverSeedHash(encoded),
+	}, nil
+}
+
+// serverSeedHash returns hex encoded SHA-256 of the hex encoded server seed
+func serverSeedHash(serverSeed string) string {
+	sum := sha256.Sum256([]byte(serverSeed))
+	return hex.EncodeToString(sum[:])
+}
+
+// SeedFairly shuffles a provably fair deck with fairShuffle and commits to the resulting order,
+// a deck can be seeded only once
+func (d *Deck) SeedFairly(clientSeed string) error {
+	if d.Fairness == nil {
+		return newNotFairError(d.ID)
+	}
+	if d.Fairness.Commitment != "" {
+		return pkg.NewConflictError(fmt.Sprintf("provably fair deck with ID %s is already seeded", d.ID))
+	}
+	fairness := *d.Fairness
+	fairness.ClientSeed = clientSeed
+	if err := fairShuffle(d.Cards, fairness.ServerSeed, fairness.ClientSeed); err != nil {
+		return err
+	}
+	fairness.Commitment = fairCommitment(fairness.ServerSeed, d.Cards)
+	d.Fairness = &fairness
+	d.Shuffled = true
+	return nil
+}
+
+// checkClientSeeded returns ConflictError when the deck is provably fair and waits for its client seed
+func (d *Deck) checkClientSeeded() error {
+	if d.Fairness != nil && d.Fairness.Commitment == "" {
+		return pkg.NewConflictError(fmt.Sprintf("provably fair deck with ID %s waits for the client seed", d.ID))
+	}
+	return nil
+}
+
+// fairShuffle shuffles cards (in place) using Fisher-Yates' algorithm driven by SeededRandomSource.
+// Its seed and stream are the first and the second 8 bytes (big endian) of HMAC-SHA256 of the client seed
+// keyed by the hex decoded server seed.
+func fairShuffle(cards []Card, serverSeed, clientSeed string) error {
+	key, err := hex.DecodeString(serverSeed)
+	if err != nil {
+		return err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(clientSeed))
+	sum := mac.Sum(nil)
+	random := NewSeededRandomSource(binary.BigEndian.Uint64(sum[:8]), binary.BigEndian.Uint64(sum[8:16]))
+	return shuffleCards(cards, random)
+}
+
+// fairCommitment returns hex encoded SHA-256 of "<server seed>:<code>,<code>,..." with codes of cards in order
+func fairCommitment(serverSeed string, cards []Card) string {
+	sum := sha256.Sum256([]byte(serverSeed + ":" + strings.Join(cardsToCodes(cards), ",")))
+	return hex.EncodeToString(sum[:])
+}
+
+// FairnessRevealed reports whether the server seed of a seeded provably fair deck can be published.
+// Cards held in piles are still in play, the order would reveal them, so the deck is exhausted only without them.
+func (d *Deck) FairnessRevealed() bool {
+	return d.Fairness != nil && d.Fairness.Commitment != "" && (d.Closed || len(d.heldCards()) == 0)
+}
+
+// fairOrder recomputes the initial order of a provably fair deck from its seeds
+func (d *Deck) fairOrder() ([]Card, error) {
+	order := d.Composition()
+	if err := fairShuffle(order, d.Fairness.ServerSeed, d.Fairness.ClientSeed); err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// VerifyFairness recomputes the initial order of the deck from the revealed seeds and checks it against the commitment
+func (d *Deck) VerifyFairness() (VerifyFairnessResponse, error) {
+	if d.Fairness == nil {
+		return VerifyFairnessResponse{}, newNotFairError(d.ID)
+	}
+	if err := d.checkClientSeeded(); err != nil {
+		return VerifyFairnessResponse{}, err
+	}
+	if !d.FairnessRevealed() {
+		return VerifyFairnessResponse{}, pkg.NewConflictError(fmt.Sprintf("deck with ID %s can be verified once it is exhausted or closed", d.ID))
+	}
+
+	order, err := d.fairOrder()
+	if err != nil {
+		return VerifyFairnessResponse{}, err
+	}
+	commitment := fairCommitment(d.Fairness.ServerSeed, order)
+	hash := serverSeedHash(d.Fairness.ServerSeed)
+	return VerifyFairnessResponse{
+		Valid:                  commitment == d.Fairness.Commitment && hash == d.Fairness.ServerSeedHash,
+		Commitment:             d.Fairness.Commitment,
+		ComputedCommitment:     commitment,
+		ServerSeedHash:         d.Fairness.ServerSeedHash,
+		ComputedServerSeedHash: hash,
+		ServerSeed:             d.Fairness.ServerSeed,
+		ClientSeed:             d.Fairness.ClientSeed,
+		Order:                  cardsToCodes(order),
+	}, nil
+}
+
+type FairnessResponse struct {
+	ServerSeedHash string `json:"server_seed_hash"`
+	// Commitment and ClientSeed are empty until the deck is seeded
+	Commitment string `json:"commitment,omitempty"`
+	ClientSeed string `json:"client_seed,omitempty"`
+	Revealed   bool   `json:"revealed"`
+	// ServerSeed and Order are published once the deck is exhausted or closed
+	ServerSeed string   `json:"server_seed,omitempty"`
+	Order      []string `json:"order,omitempty"`
+}
+
+// conceal removes the revealed server seed and order from the response,
+// they reveal cards of a hidden deck to callers who are not its owner
+func (f *FairnessResponse) conceal() {
+	if f == nil {
+		return
+	}
+	f.Revealed = false
+	f.ServerSeed = ""
+	f.Order = nil
+}
+
+// NewFairnessResponse returns nil for decks which are not provably fair
+func NewFairnessResponse(deck Deck) *FairnessResponse {
+	if deck.Fairness == nil {
+		return nil
+	}
+	response := &FairnessResponse{
+		ServerSeedHash: deck.Fairness.ServerSeedHash,
+		Commitment:     deck.Fairness.Commitment,
+		ClientSeed:     deck.Fairness.ClientSeed,
+	}
+	if !deck.FairnessRevealed() {
+		return response
+	}
+	order, err := deck.fairOrder()
+	if err != nil {
+		return response
+	}
+	response.Revealed = true
+	response.ServerSeed = deck.Fairness.ServerSeed
+	response.Order = cardsToCodes(order)
+	return response
+}
+
+type VerifyFairnessResponse struct {
+	Valid                  bool     `json:"valid"`
+	Commitment             string   `json:"commitment"`
+	ComputedCommitment     string   `json:"computed_commitment"`
+	ServerSeedHash         string   `json:"server_seed_hash"`
+	ComputedServerSeedHash string   `json:"computed_server_seed_hash"`
+	ServerSeed             string   `json:"server_seed"`
+	ClientSeed             string   `json:"client_seed"`
+	Order                  []string `json:"order"`
+}
+
+func newNotFairError(deckID uuid.UUID) *pkg.ConflictError {
+	return pkg.NewConflictError(fmt.Sprintf("deck with ID %s is not provably fair", deckID))
+}
@@ -0,0 +1,204 @@
+package internal
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/prathoss/cards/pkg"
+)
+
+func TestNewDeck_Fair(t *testing.T) {
+	deck, err := NewDeck(DeckOptions{Fair: true, Shuffled: true}, NewFixtureRandomSource(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// only the hash of the server seed is known until the client seed is sent
+	if deck.Shuffled || deck.Fairness == nil || deck.Fairness.Commitment != "" {
+		t.Fatalf("provably fair deck is shuffled before it is seeded: %+v", deck.Fairness)
+	}
+	if deck.Fairness.ServerSeedHash != serverSeedHash(deck.Fairness.ServerSeed) {
+		t.Errorf("server seed hash does not match the server seed")
+	}
+	if err := deck.checkClientSeeded(); err == nil {
+		t.Errorf("Deck.checkClientSeeded() of a deck without client seed error = nil")
+	}
+
+	if err := deck.SeedFairly("lucky"); err != nil {
+		t.Fatal(err)
+	}
+	if !deck.Shuffled {
+		t.Errorf("provably fair deck is not marked as shuffled")
+	}
+	if deck.Fairness.ClientSeed != "lucky" || len(deck.Fairness.ServerSeed) != 2*serverSeedSize {
+		t.Fatalf("unexpected fairness %+v", deck.Fairness)
+	}
+	if err := deck.checkClientSeeded(); err != nil {
+		t.Errorf("Deck.checkClientSeeded() error = %v", err)
+	}
+	if got := fairCommitment(deck.Fairness.ServerSeed, deck.Cards); got != deck.Fairness.Commitment {
+		t.Errorf("commitment does not match the order of the deck, got %s, want %s", got, deck.Fairness.Commitment)
+	}
+	order, err := deck.fairOrder()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(cardsToCodes(order), cardsToCodes(deck.Cards)) {
+		t.Errorf("order recomputed from seeds differs from the deck")
+	}
+
+	var conflictError *pkg.ConflictError
+	if err := deck.SeedFairly("again"); !errors.As(err, &conflictError) {
+		t.Errorf("Deck.SeedFairly() of a seeded deck error = %v, want ConflictError", err)
+	}
+	notFair := Deck{}
+	if err := notFair.SeedFairly("lucky"); !errors.As(err, &conflictError) {
+		t.Errorf("Deck.SeedFairly() of a deck which is not provably fair error = %v, want ConflictError", err)
+	}
+}
+
+func TestFairShuffle_ClientSeedChangesOrder(t *testing.T) {
+	serverSeed := "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+	template := deckTemplates[DeckTypeStandard]
+
+	first := generateAllCardsCombinations(template)
+	if err := fairShuffle(first, serverSeed, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	again := generateAllCardsCombinations(template)
+	if err := fairShuffle(again, serverSeed, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	other := generateAllCardsCombinations(template)
+	if err := fairShuffle(other, serverSeed, "bob"); err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(cardsToCodes(first), cardsToCodes(again)) {
+		t.Errorf("same seeds produced different orders")
+	}
+	if slices.Equal(cardsToCodes(first), cardsToCodes(other)) {
+		t.Errorf("different client seeds produced the same order")
+	}
+}
+
+func TestVerifyFairness(t *testing.T) {
+	tests := []struct {
+		name      string
+		prepare   func(deck *Deck)
+		wantValid bool
+		wantErr   bool
+	}{
+		{
+			name:    "Not revealed while cards remain",
+			prepare: func(deck *Deck) {},
+			wantErr: true,
+		},
+		{
+			name:      "Closed deck",
+			prepare:   func(deck *Deck) { deck.Close() },
+			wantValid: true,
+		},
+		{
+			name:      "Exhausted deck",
+			prepare:   func(deck *Deck) { deck.Cards = nil },
+			wantValid: true,
+		},
+		{
+			name: "Tampered server seed hash",
+			prepare: func(deck *Deck) {
+				deck.Close()
+				deck.Fairness.ServerSeedHash = serverSeedHash("other")
+			},
+			wantValid: false,
+		},
+		{
+			name: "Tampered commitment",
+			prepare: func(deck *Deck) {
+				deck.Close()
+				deck.Fairness.Commitment = fairCommitment(deck.Fairness.ServerSeed, nil)
+			},
+			wantValid: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deck, err := NewDeck(DeckOptions{Fair: true}, CryptoRandomSource{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := deck.SeedFairly("lucky"); err != nil {
+				t.Fatal(err)
+			}
+			tt.prepare(&deck)
+
+			verification, err := deck.VerifyFairness()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Deck.VerifyFairness() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if verification.Valid != tt.wantValid {
+				t.Errorf("Deck.VerifyFairness() valid = %v, want %v", verification.Valid, tt.wantValid)
+			}
+		})
+	}
+}
+
+func TestVerifyFairness_NotFair(t *testing.T) {
+	deck := Deck{Closed: true}
+
+	_, err := deck.VerifyFairness()
+
+	var conflictError *pkg.ConflictError
+	if !errors.As(err, &conflictError) {
+		t.Errorf("expected ConflictError, got %v", err)
+	}
+}
+
+func TestNewFairnessResponse(t *testing.T) {
+	deck, err := NewDeck(DeckOptions{Fair: true}, CryptoRandomSource{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deck.Close()
+	response := NewFairnessResponse(deck)
+	if response.Revealed || response.ServerSeedHash == "" || response.Commitment != "" {
+		t.Errorf("fairness of a deck without client seed is revealed: %+v", response)
+	}
+	deck.Closed = false
+
+	if err := deck.SeedFairly("lucky"); err != nil {
+		t.Fatal(err)
+	}
+	response = NewFairnessResponse(deck)
+	if response.Revealed || response.ServerSeed != "" || response.Order != nil {
+		t.Errorf("fairness of an open deck with cards is revealed: %+v", response)
+	}
+
+	order := cardsToCodes(deck.Cards)
+	dealt := deck
+	if _, err := dealt.Deal([]string{"north", "east", "south", "west"}, 13); err != nil {
+		t.Fatal(err)
+	}
+	response = NewFairnessResponse(dealt)
+	if response.Revealed || response.ServerSeed != "" || response.Order != nil {
+		t.Errorf("fairness of a deck with cards in piles is revealed: %+v", response)
+	}
+
+	if _, err := deck.DrawCards(52); err != nil {
+		t.Fatal(err)
+	}
+	response = NewFairnessResponse(deck)
+	if !response.Revealed || response.ServerSeed != deck.Fairness.ServerSeed || !slices.Equal(response.Order, order) {
+		t.Errorf("fairness of an exhausted deck is not revealed: %+v", response)
+	}
+
+	if NewFairnessResponse(Deck{}) != nil {
+		t.Errorf("fairness response of a deck which is not provably fair should be nil")
+	}
+}
@@ -0,0 +1,110 @@
+package internal
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prathoss/cards/pkg"
+)
+
+const (
+	DeckEventCreated       = "created"
+	DeckEventCloned        = "cloned"
+	DeckEventDrawn         = "drawn"
+	DeckEventReturned      = "returned"
+	DeckEventShuffled      = "shuffled"
+	DeckEventCut           = "cut"
+	DeckEventDealt         = "dealt"
+	DeckEventDrawnToPile   = "drawn_to_pile"
+	DeckEventDrawnFromPile = "drawn_from_pile"
+	DeckEventPileShuffled  = "pile_shuffled"
+	DeckEventClosed        = "closed"
+	DeckEventSeeded        = "seeded"
+	DeckEventUndone        = "undone"
+)
+
+const (
+	// DefaultHistoryLimit is the number of events of a history page when the limit is not given
+	DefaultHistoryLimit = 50
+	// MaxHistoryLimit is the maximal number of events of a history page
+	MaxHistoryLimit = 500
+	// MaxHistoryEvents is the number of latest events kept for a deck, older events are dropped,
+	// so the history of a long-lived deck stays well below the MongoDB document size limit
+	MaxHistoryEvents = 1000
+)
+
+// DeckEvent records a single modification of a deck, events are never modified once recorded
+type DeckEvent struct {
+	DeckID uuid.UUID `bson:"deck_id"`
+	// Seq is the version of the deck after the modification, it orders events of the deck
+	Seq           int64     `bson:"seq"`
+	Type          string    `bson:"type"`
+	CorrelationID uuid.UUID `bson:"correlation_id"`
+	At            time.Time `bson:"at"`
+	// Cards affected by the modification, like drawn or returned cards
+	Cards []Card `bson:"cards,omitempty"`
+	// Piles affected by the modification, hands for dealing
+	Piles    []string `bson:"piles,omitempty"`
+	Position int      `bson:"position,omitempty"`
+}
+
+// newDeckEvent completes event describing the modification which produced deck
+func newDeckEvent(ctx context.Context, deck Deck, event DeckEvent) DeckEvent {
+	event.DeckID = deck.ID
+	event.Seq = deck.Version
+	event.CorrelationID = pkg.GetCorrelationIDCtx(ctx)
+	event.At = deck.LastUsedAt
+	return event
+}
+
+// requiresClientSeed reports whether the modification recorded by the event is forbidden
+// until a provably fair deck is seeded
+func (e DeckEvent) requiresClientSeed() bool {
+	return e.Type != DeckEventSeeded && e.Type != DeckEventClosed
+}
+
+type DeckEventResponse struct {
+	Seq           int64          `json:"seq"`
+	Type          string         `json:"type"`
+	CorrelationID uuid.UUID      `json:"correlation_id"`
+	At            time.Time      `json:"at"`
+	Cards         []CardResponse `json:"cards,omitempty"`
+	Piles         []string       `json:"piles,omitempty"`
+	Position      int            `json:"position,omitempty"`
+}
+
+type HistoryResponse struct {
+	DeckID uuid.UUID           `json:"deck_id"`
+	Events []DeckEventResponse `json:"events"`
+	// Next is the value of the after parameter of the next page, it is omitted on the last page
+	Next *int64 `json:"next,omitempty"`
+}
+
+// NewHistoryResponse returns a page of events, a full page is expected to be followed by another one
+func NewHistoryResponse(deckID uuid.UUID, events []DeckEvent, limit int) HistoryResponse {
+	response := HistoryResponse{
+		DeckID: deckID,
+		Events: make([]DeckEventResponse, 0, len(events)),
+	}
+	for _, event := range events {
+		var cards []CardResponse
+		if len(event.Cards) > 0 {
+			cards = NewCardsResponse(event.Cards).Cards
+		}
+		response.Events = append(response.Events, DeckEventResponse{
+			Seq:           event.Seq,
+			Type:          event.Type,
+			CorrelationID: event.CorrelationID,
+			At:            event.At,
+			Cards:         cards,
+			Piles:         event.Piles,
+			Position:      event.Position,
+		})
+	}
+	if len(events) > 0 && len(events) == limit {
+		next := events[len(events)-1].Seq
+		response.Next = &next
+	}
+	return response
+}
@@ -0,0 +1,43 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestNewHistoryResponse(t *testing.T) {
+	events := []DeckEvent{
+		{Seq: 3, Type: DeckEventDrawn, Cards: []Card{{Value: CardValueAce, Suit: CardSuitSpades}}},
+		{Seq: 4, Type: DeckEventShuffled},
+	}
+	lastSeq := int64(4)
+
+	tests := []struct {
+		name     string
+		events   []DeckEvent
+		limit    int
+		wantNext *int64
+	}{
+		{name: "FullPage", events: events, limit: 2, wantNext: &lastSeq},
+		{name: "LastPage", events: events, limit: 3, wantNext: nil},
+		{name: "NoEvents", events: nil, limit: 2, wantNext: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewHistoryResponse(uuid.New(), tt.events, tt.limit)
+			if len(got.Events) != len(tt.events) {
+				t.Fatalf("NewHistoryResponse() events = %d, want %d", len(got.Events), len(tt.events))
+			}
+			if (got.Next == nil) != (tt.wantNext == nil) || (got.Next != nil && *got.Next != *tt.wantNext) {
+				t.Errorf("NewHistoryResponse() next = %v, want %v", got.Next, tt.wantNext)
+			}
+		})
+	}
+
+	got := NewHistoryResponse(uuid.New(), events, 2)
+	if len(got.Events[0].Cards) != 1 || got.Events[0].Cards[0].Code != "AS" || got.Events[1].Cards != nil {
+		t.Errorf("NewHistoryResponse() cards = %v and %v", got.Events[0].Cards, got.Events[1].Cards)
+	}
+}
@@ -0,0 +1,44 @@
+package internal
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prathoss/cards/pkg"
+)
+
+// now returns the current time in UTC truncated to milliseconds, the precision of dates stored by MongoDB
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Millisecond)
+}
+
+// touch records a modification of the deck at now and postpones its expiry
+func (d *Deck) touch(now time.Time) {
+	d.LastUsedAt = now
+	if d.TTL > 0 {
+		expiresAt := now.Add(d.TTL)
+		d.ExpiresAt = &expiresAt
+	}
+}
+
+// Expired reports whether the deck expired before now
+func (d *Deck) Expired(now time.Time) bool {
+	return d.ExpiresAt != nil && !d.ExpiresAt.After(now)
+}
+
+// checkNotExpired returns GoneError when the deck expired before now
+func (d *Deck) checkNotExpired(now time.Time) error {
+	if d.Expired(now) {
+		return newDeckExpiredError(d.ID)
+	}
+	return nil
+}
+
+func newDeckExpiredError(deckID uuid.UUID) *pkg.GoneError {
+	return pkg.NewGoneError(fmt.Sprintf("deck with ID %s expired", deckID))
+}
+
+func newDeckNotFoundError(deckID uuid.UUID) *pkg.NotFoundError {
+	return pkg.NewNotFoundError(fmt.Sprintf("deck with ID %s not found", deckID))
+}
@@ -0,0 +1,64 @@
+package internal
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prathoss/cards/pkg"
+)
+
+func TestDeck_Touch(t *testing.T) {
+	usedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		ttl           time.Duration
+		wantExpiresAt *time.Time
+	}{
+		{name: "WithoutTTL", ttl: 0, wantExpiresAt: nil},
+		{name: "WithTTL", ttl: time.Hour, wantExpiresAt: func() *time.Time { t := usedAt.Add(time.Hour); return &t }()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deck := Deck{TTL: tt.ttl}
+
+			deck.touch(usedAt)
+
+			if !deck.LastUsedAt.Equal(usedAt) {
+				t.Errorf("last used at = %v, want %v", deck.LastUsedAt, usedAt)
+			}
+			if (deck.ExpiresAt == nil) != (tt.wantExpiresAt == nil) || (deck.ExpiresAt != nil && !deck.ExpiresAt.Equal(*tt.wantExpiresAt)) {
+				t.Errorf("expires at = %v, want %v", deck.ExpiresAt, tt.wantExpiresAt)
+			}
+		})
+	}
+}
+
+func TestDeck_CheckNotExpired(t *testing.T) {
+	expiresAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		deck    Deck
+		now     time.Time
+		wantErr bool
+	}{
+		{name: "NeverExpires", deck: Deck{}, now: expiresAt},
+		{name: "BeforeExpiry", deck: Deck{ExpiresAt: &expiresAt}, now: expiresAt.Add(-time.Millisecond)},
+		{name: "AtExpiry", deck: Deck{ExpiresAt: &expiresAt}, now: expiresAt, wantErr: true},
+		{name: "AfterExpiry", deck: Deck{ExpiresAt: &expiresAt}, now: expiresAt.Add(time.Hour), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.deck.checkNotExpired(tt.now)
+
+			var goneError *pkg.GoneError
+			if errors.As(err, &goneError) != tt.wantErr {
+				t.Errorf("Deck.checkNotExpired() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
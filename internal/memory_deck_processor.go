@@ -0,0 +1,324 @@
+package internal
+
+import (
+	"cmp"
+	"context"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var _ DeckProcessor = (*MemoryDeckProcessor)(nil)
+
+// MemoryDeckProcessor keeps decks in process memory.
+// It is intended for local development and tests, decks are lost on restart.
+type MemoryDeckProcessor struct {
+	mu     sync.RWMutex
+	decks  map[uuid.UUID]*memoryDeck
+	random RandomSource
+}
+
+// memoryDeck guards a single deck, so operations on different decks do not block each other
+type memoryDeck struct {
+	mu     sync.Mutex
+	deck   Deck
+	events []DeckEvent
+}
+
+func NewMemoryDeckProcessor(random RandomSource) *MemoryDeckProcessor {
+	return &MemoryDeckProcessor{
+		decks:  make(map[uuid.UUID]*memoryDeck),
+		random: random,
+	}
+}
+
+func (m *MemoryDeckProcessor) Create(ctx context.Context, options DeckOptions) (Deck, error) {
+	deck, err := NewDeck(options, m.random)
+	if err != nil {
+		return Deck{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.decks[deck.ID] = &memoryDeck{
+		deck:   cloneDeck(deck),
+		events: []DeckEvent{newDeckEvent(ctx, deck, DeckEvent{Type: DeckEventCreated})},
+	}
+	return deck, nil
+}
+
+func (m *MemoryDeckProcessor) Get(_ context.Context, deckID uuid.UUID) (Deck, error) {
+	stored, err := m.lookup(deckID)
+	if err != nil {
+		return Deck{}, err
+	}
+
+	stored.mu.Lock()
+	defer stored.mu.Unlock()
+	if err := stored.deck.checkNotExpired(now()); err != nil {
+		return Deck{}, err
+	}
+	return cloneDeck(stored.deck), nil
+}
+
+func (m *MemoryDeckProcessor) Clone(ctx context.Context, deckID uuid.UUID) (Deck, error) {
+	deck, err := m.Get(ctx, deckID)
+	if err != nil {
+		return Deck{}, err
+	}
+
+	fork := deck.Fork()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.decks[fork.ID] = &memoryDeck{
+		deck:   cloneDeck(fork),
+		events: []DeckEvent{newDeckEvent(ctx, fork, DeckEvent{Type: DeckEventCloned})},
+	}
+	return fork, nil
+}
+
+func (m *MemoryDeckProcessor) DrawCards(ctx context.Context, deckID uuid.UUID, selector CardSelector) (Deck, []Card, error) {
+	var cards []Card
+	deck, err := m.update(ctx, deckID, func(deck *Deck) (DeckEvent, error) {
+		var err error
+		cards, err = deck.Draw(selector, m.random)
+		return DeckEvent{Type: DeckEventDrawn, Cards: cards}, err
+	})
+	if err != nil {
+		return Deck{}, nil, err
+	}
+	return deck, slices.Clone(cards), nil
+}
+
+func (m *MemoryDeckProcessor) ReturnCards(ctx context.Context, deckID uuid.UUID, codes []string, position string) (Deck, error) {
+	return m.update(ctx, deckID, func(deck *Deck) (DeckEvent, error) {
+		cards, err := deck.cardsToReturn(codes)
+		if err != nil {
+			return DeckEvent{}, err
+		}
+		return DeckEvent{Type: DeckEventReturned, Cards: cards}, deck.ReturnCards(codes, position, m.random)
+	})
+}
+
+func (m *MemoryDeckProcessor) Shuffle(ctx context.Context, deckID uuid.UUID, options ShuffleOptions) (Deck, error) {
+	return m.update(ctx, deckID, func(deck *Deck) (DeckEvent, error) {
+		return DeckEvent{Type: DeckEventShuffled}, deck.Shuffle(options, m.random)
+	})
+}
+
+func (m *MemoryDeckProcessor) DrawToPile(ctx context.Context, deckID uuid.UUID, pile string, count int) (Deck, error) {
+	return m.update(ctx, deckID, func(deck *Deck) (DeckEvent, error) {
+		if err := deck.DrawToPile(pile, count); err != nil {
+			return DeckEvent{}, err
+		}
+		return DeckEvent{Type: DeckEventDrawnToPile, Cards: deck.Piles[pile][:count], Piles: []string{pile}}, nil
+	})
+}
+
+func (m *MemoryDeckProcessor) Deal(ctx context.Context, deckID uuid.UUID, hands []string, count int) (Deck, [][]Card, error) {
+	var dealt [][]Card
+	deck, err := m.update(ctx, deckID, func(deck *Deck) (DeckEvent, error) {
+		var err error
+		dealt, err = deck.Deal(hands, count)
+		return DeckEvent{Type: DeckEventDealt, Cards: slices.Concat(dealt...), Piles: hands}, err
+	})
+	if err != nil {
+		return Deck{}, nil, err
+	}
+	return deck, dealt, nil
+}
+
+func (m *MemoryDeckProcessor) DrawFromPile(ctx context.Context, deckID uuid.UUID, pile string, selector CardSelector) (Deck, []Card, error) {
+	var cards []Card
+	deck, err := m.update(ctx, deckID, func(deck *Deck) (DeckEvent, error) {
+		var err error
+		cards, err = deck.DrawFromPile(pile, selector, m.random)
+		return DeckEvent{Type: DeckEventDrawnFromPile, Cards: cards, Piles: []string{pile}}, err
+	})
+	if err != nil {
+		return Deck{}, nil, err
+	}
+	return deck, slices.Clone(cards), nil
+}
+
+func (m *MemoryDeckProcessor) ShufflePile(ctx context.Context, deckID uuid.UUID, pile string) (Deck, error) {
+	return m.update(ctx, deckID, func(deck *Deck) (DeckEvent, error) {
+		return DeckEvent{Type: DeckEventPileShuffled, Piles: []string{pile}}, deck.ShufflePile(pile, m.random)
+	})
+}
+
+func (m *MemoryDeckProcessor) Cut(ctx context.Context, deckID uuid.UUID, options CutOptions) (Deck, int, error) {
+	var position int
+	deck, err := m.update(ctx, deckID, func(deck *Deck) (DeckEvent, error) {
+		var err error
+		position, err = deck.Cut(options, m.random)
+		return DeckEvent{Type: DeckEventCut, Position: position}, err
+	})
+	if err != nil {
+		return Deck{}, 0, err
+	}
+	return deck, position, nil
+}
+
+func (m *MemoryDeckProcessor) SeedFairness(ctx context.Context, deckID uuid.UUID, clientSeed string) (Deck, error) {
+	return m.update(ctx, deckID, func(deck *Deck) (DeckEvent, error) {
+		return DeckEvent{Type: DeckEventSeeded}, deck.SeedFairly(clientSeed)
+	})
+}
+
+func (m *MemoryDeckProcessor) Close(ctx context.Context, deckID uuid.UUID) (Deck, error) {
+	return m.update(ctx, deckID, func(deck *Deck) (DeckEvent, error) {
+		deck.Close()
+		return DeckEvent{Type: DeckEventClosed}, nil
+	})
+}
+
+func (m *MemoryDeckProcessor) Undo(ctx context.Context, deckID uuid.UUID) (Deck, error) {
+	return m.update(ctx, deckID, func(deck *Deck) (DeckEvent, error) {
+		return DeckEvent{Type: DeckEventUndone}, deck.Undo()
+	})
+}
+
+func (m *MemoryDeckProcessor) History(_ context.Context, deckID uuid.UUID, after int64, limit int) ([]DeckEvent, error) {
+	stored, err := m.lookup(deckID)
+	if err != nil {
+		return nil, err
+	}
+
+	stored.mu.Lock()
+	defer stored.mu.Unlock()
+	if err := stored.deck.checkNotExpired(now()); err != nil {
+		return nil, err
+	}
+	// events are appended in the order of their sequence numbers
+	i, _ := slices.BinarySearchFunc(stored.events, after+1, func(event DeckEvent, seq int64) int {
+		return cmp.Compare(event.Seq, seq)
+	})
+	events := stored.events[i:]
+	if len(events) > limit {
+		events = events[:limit]
+	}
+	return slices.Clone(events), nil
+}
+
+func (m *MemoryDeckProcessor) Delete(ctx context.Context, deckID uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored, ok := m.decks[deckID]
+	if !ok {
+		return newDeckNotFoundError(deckID)
+	}
+	stored.mu.Lock()
+	defer stored.mu.Unlock()
+	// expired decks are left to Sweep
+	if err := stored.deck.checkNotExpired(now()); err != nil {
+		return err
+	}
+	if err := stored.deck.checkIfMatch(ctx); err != nil {
+		return err
+	}
+	delete(m.decks, deckID)
+	return nil
+}
+
+// Sweep deletes decks which expired before now and returns how many were deleted
+func (m *MemoryDeckProcessor) Sweep(now time.Time) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	deleted := 0
+	for id, stored := range m.decks {
+		stored.mu.Lock()
+		expired := stored.deck.Expired(now)
+		stored.mu.Unlock()
+		if expired {
+			delete(m.decks, id)
+			deleted++
+		}
+	}
+	return deleted
+}
+
+// RunSweeper sweeps expired decks every interval until ctx is done
+func (m *MemoryDeckProcessor) RunSweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if deleted := m.Sweep(now()); deleted > 0 {
+				slog.InfoContext(ctx, "expired decks deleted", slog.Int("count", deleted))
+			}
+		}
+	}
+}
+
+// update applies fn to a copy of the deck while holding its lock, closed decks and decks not matching If-Match
+// of the context are never updated.
+// The copy replaces the stored deck only when fn succeeds, so failed operations leave the deck untouched.
+// The event returned by fn is recorded together with the modification, which can be undone unless it is an undo.
+func (m *MemoryDeckProcessor) update(ctx context.Context, deckID uuid.UUID, fn func(deck *Deck) (DeckEvent, error)) (Deck, error) {
+	stored, err := m.lookup(deckID)
+	if err != nil {
+		return Deck{}, err
+	}
+
+	stored.mu.Lock()
+	defer stored.mu.Unlock()
+	updatedAt := now()
+	if err := stored.deck.checkNotExpired(updatedAt); err != nil {
+		return Deck{}, err
+	}
+	if err := stored.deck.checkIfMatch(ctx); err != nil {
+		return Deck{}, err
+	}
+	if err := stored.deck.checkNotClosed(); err != nil {
+		return Deck{}, err
+	}
+	deck := cloneDeck(stored.deck)
+	event, err := fn(&deck)
+	if err != nil {
+		return Deck{}, err
+	}
+	if err := stored.deck.checkClientSeeded(); err != nil && event.requiresClientSeed() {
+		return Deck{}, err
+	}
+	deck.rememberSnapshot(stored.deck.snapshot(), event)
+	deck.touch(updatedAt)
+	deck.Version++
+	event.Cards = slices.Clone(event.Cards)
+	stored.deck = deck
+	stored.events = append(stored.events, newDeckEvent(ctx, deck, event))
+	if dropped := len(stored.events) - MaxHistoryEvents; dropped > 0 {
+		stored.events = slices.Delete(stored.events, 0, dropped)
+	}
+	return cloneDeck(deck), nil
+}
+
+func (m *MemoryDeckProcessor) lookup(deckID uuid.UUID) (*memoryDeck, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	stored, ok := m.decks[deckID]
+	if !ok {
+		return nil, newDeckNotFoundError(deckID)
+	}
+	return stored, nil
+}
+
+// cloneDeck returns a copy of the deck which does not share any slices with the original
+func cloneDeck(deck Deck) Deck {
+	deck.Selection = slices.Clone(deck.Selection)
+	if deck.Fairness != nil {
+		fairness := *deck.Fairness
+		deck.Fairness = &fairness
+	}
+	deck.Cards = slices.Clone(deck.Cards)
+	deck.Piles = clonePiles(deck.Piles)
+	// snapshots are never modified, only replaced
+	deck.Snapshots = slices.Clone(deck.Snapshots)
+	return deck
+}
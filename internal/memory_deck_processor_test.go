@@ -0,0 +1,90 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prathoss/cards/pkg"
+)
+
+func TestMemoryDeckProcessor_Get_DoesNotShareCards(t *testing.T) {
+	processor := NewMemoryDeckProcessor(CryptoRandomSource{})
+	ctx := context.Background()
+
+	deck, err := processor.Create(ctx, DeckOptions{Cards: []string{"AS", "KH"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := processor.Get(ctx, deck.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got.Cards[0] = Card{}
+
+	stored, err := processor.Get(ctx, deck.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Cards[0].Code() != "AS" {
+		t.Errorf("modifying returned deck changed stored deck, got top card %s", stored.Cards[0].Code())
+	}
+}
+
+func TestMemoryDeckProcessor_Sweep(t *testing.T) {
+	processor := NewMemoryDeckProcessor(CryptoRandomSource{})
+	ctx := context.Background()
+
+	expiring, err := processor.Create(ctx, DeckOptions{TTL: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lasting, err := processor.Create(ctx, DeckOptions{TTL: 3 * time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	forever, err := processor.Create(ctx, DeckOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if deleted := processor.Sweep(now().Add(2 * time.Hour)); deleted != 1 {
+		t.Errorf("Sweep() deleted = %d, want 1", deleted)
+	}
+
+	var notFoundError *pkg.NotFoundError
+	if _, err := processor.Get(ctx, expiring.ID); !errors.As(err, &notFoundError) {
+		t.Errorf("Get() of swept deck error = %v, want NotFoundError", err)
+	}
+	for _, deck := range []Deck{lasting, forever} {
+		if _, err := processor.Get(ctx, deck.ID); err != nil {
+			t.Errorf("Get() of deck which did not expire error = %v", err)
+		}
+	}
+}
+
+func TestMemoryDeckProcessor_Expired(t *testing.T) {
+	processor := NewMemoryDeckProcessor(CryptoRandomSource{})
+	ctx := context.Background()
+
+	deck, err := processor.Create(ctx, DeckOptions{TTL: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, err := processor.lookup(deck.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expiresAt := now().Add(-time.Second)
+	stored.deck.ExpiresAt = &expiresAt
+
+	var goneError *pkg.GoneError
+	if _, err := processor.Get(ctx, deck.ID); !errors.As(err, &goneError) {
+		t.Errorf("Get() error = %v, want GoneError", err)
+	}
+	if _, _, err := processor.DrawCards(ctx, deck.ID, CardSelector{Count: 1}); !errors.As(err, &goneError) {
+		t.Errorf("DrawCards() error = %v, want GoneError", err)
+	}
+}
@@ -0,0 +1,73 @@
+package internal
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/prathoss/cards/pkg"
+)
+
+// ownerTokenSize is the number of random bytes of an owner token
+const ownerTokenSize = 32
+
+// NewOwnerToken returns a random token authorizing its holder to see the cards of a hidden deck
+func NewOwnerToken() (string, error) {
+	token := make([]byte, ownerTokenSize)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(token), nil
+}
+
+// hashOwnerToken returns the hex encoded SHA-256 of the token, only the hash is stored with the deck
+func hashOwnerToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// IsOwner reports whether token is the owner token of the deck
+func (d *Deck) IsOwner(token string) bool {
+	if d.OwnerTokenHash == "" || token == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(hashOwnerToken(token)), []byte(d.OwnerTokenHash)) == 1
+}
+
+// CheckCanReveal returns ForbiddenError when the deck is hidden and token is not its owner token.
+// Every response revealing the order of cards, like peeks, piles or the history, is restricted by it.
+func (d *Deck) CheckCanReveal(token string) error {
+	if d.Hidden && !d.IsOwner(token) {
+		return pkg.NewForbiddenError(fmt.Sprintf("cards of hidden deck with ID %s can only be revealed to its owner", d.ID))
+	}
+	return nil
+}
+
+// HiddenDeckResponse describes a hidden deck without revealing its cards
+type HiddenDeckResponse struct {
+	CreateDeckResponse
+	// Suits holds the number of remaining cards of each suit
+	Suits map[string]int `json:"suits"`
+}
+
+func NewHiddenDeckResponse(deck Deck) HiddenDeckResponse {
+	response := HiddenDeckResponse{
+		CreateDeckResponse: NewCreateDeckResponse(deck),
+		Suits:              countBySuit(deck.Cards),
+	}
+	response.Fairness.conceal()
+	return response
+}
+
+// countBySuit returns the number of cards of each suit, jokers have no suit and are not counted
+func countBySuit(cards []Card) map[string]int {
+	suits := make(map[string]int)
+	for _, card := range cards {
+		if card.Value != CardValueJoker {
+			suits[card.Suit]++
+		}
+	}
+	return suits
+}
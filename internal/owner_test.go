@@ -0,0 +1,79 @@
+package internal
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/prathoss/cards/pkg"
+)
+
+func TestDeck_IsOwner(t *testing.T) {
+	token, err := NewOwnerToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	deck, err := NewDeck(DeckOptions{Hidden: true, OwnerToken: token}, CryptoRandomSource{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		deck  Deck
+		token string
+		want  bool
+	}{
+		{name: "Owner", deck: deck, token: token, want: true},
+		{name: "WrongToken", deck: deck, token: token + "0", want: false},
+		{name: "EmptyToken", deck: deck, token: "", want: false},
+		{name: "DeckWithoutOwner", deck: Deck{}, token: "", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.deck.IsOwner(tt.token); got != tt.want {
+				t.Errorf("Deck.IsOwner() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if deck.OwnerTokenHash == token {
+		t.Errorf("owner token is stored in plain text")
+	}
+}
+
+func TestDeck_CheckCanReveal(t *testing.T) {
+	token, err := NewOwnerToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hidden, err := NewDeck(DeckOptions{Hidden: true, OwnerToken: token}, CryptoRandomSource{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	visible, err := NewDeck(DeckOptions{}, CryptoRandomSource{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		deck          Deck
+		token         string
+		wantForbidden bool
+	}{
+		{name: "Visible", deck: visible, token: "", wantForbidden: false},
+		{name: "HiddenOwner", deck: hidden, token: token, wantForbidden: false},
+		{name: "HiddenAnonymous", deck: hidden, token: "", wantForbidden: true},
+		{name: "HiddenWrongToken", deck: hidden, token: "wrong", wantForbidden: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.deck.CheckCanReveal(tt.token)
+			var forbiddenError *pkg.ForbiddenError
+			if errors.As(err, &forbiddenError) != tt.wantForbidden {
+				t.Errorf("Deck.CheckCanReveal() error = %v, wantForbidden %v", err, tt.wantForbidden)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package internal
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/prathoss/cards/pkg"
+)
+
+// pileNamePattern restricts pile names, so they are safe to use in URLs and as MongoDB document keys
+var pileNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// Pile returns cards of the named pile, the top of the pile is first
+func (d *Deck) Pile(name string) ([]Card, error) {
+	pile, ok := d.Piles[name]
+	if !ok {
+		return nil, newPileNotFoundError(d.ID, name)
+	}
+	return pile, nil
+}
+
+// DrawToPile draws count cards from the top of the deck and puts them on top of the named pile.
+// The pile is created when it does not exist yet.
+func (d *Deck) DrawToPile(name string, count int) error {
+	cards, err := d.DrawCards(count)
+	if err != nil {
+		return err
+	}
+	if d.Piles == nil {
+		d.Piles = make(map[string][]Card)
+	}
+	d.Piles[name] = append(slices.Clone(cards), d.Piles[name]...)
+	return nil
+}
+
+// MaxHands is the maximal number of hands dealt at once
+const MaxHands = 32
+
+// Deal deals count cards to each of the hands round-robin from the top of the deck, like a dealer does.
+// Each hand is a pile, dealt cards are put on top of it in the order they were dealt, like DrawToPile does.
+// The returned cards are in the order of hands.
+func (d *Deck) Deal(hands []string, count int) ([][]Card, error) {
+	if count < 0 {
+		return nil, newNegativeCountError()
+	}
+	// checked before multiplying, so huge counts can not overflow into a small number of cards
+	if len(hands) > 0 && count > len(d.Cards)/len(hands) {
+		return nil, newNotEnoughCardsError()
+	}
+	cards, err := d.DrawCards(count * len(hands))
+	if err != nil {
+		return nil, err
+	}
+
+	dealt := make([][]Card, len(hands))
+	for i, card := range cards {
+		dealt[i%len(hands)] = append(dealt[i%len(hands)], card)
+	}
+	if d.Piles == nil {
+		d.Piles = make(map[string][]Card)
+	}
+	for i, hand := range hands {
+		d.Piles[hand] = append(slices.Clone(dealt[i]), d.Piles[hand]...)
+	}
+	return dealt, nil
+}
+
+// DrawFromPile removes cards matching the selector from the named pile and returns them
+func (d *Deck) DrawFromPile(name string, selector CardSelector, random RandomSource) ([]Card, error) {
+	pile, err := d.Pile(name)
+	if err != nil {
+		return nil, err
+	}
+	cards, rest, err := takeCards(pile, selector, "pile", d.randomSource(random))
+	if err != nil {
+		return nil, err
+	}
+	d.Piles[name] = rest
+	return cards, nil
+}
+
+// ShufflePile shuffles cards of the named pile (in place)
+func (d *Deck) ShufflePile(name string, random RandomSource) error {
+	pile, err := d.Pile(name)
+	if err != nil {
+		return err
+	}
+	return shuffleCards(pile, d.randomSource(random))
+}
+
+type PileResponse struct {
+	DeckID    uuid.UUID `json:"deck_id"`
+	Name      string    `json:"name"`
+	Remaining int       `json:"remaining"`
+	// Cards are nil in responses to modifications of hidden decks sent to others than the owner
+	Cards []CardResponse `json:"cards"`
+}
+
+func NewPileResponse(deckID uuid.UUID, name string, cards []Card) PileResponse {
+	return PileResponse{
+		DeckID:    deckID,
+		Name:      name,
+		Remaining: len(cards),
+		Cards:     NewCardsResponse(cards).Cards,
+	}
+}
+
+type DealResponse struct {
+	DeckID    uuid.UUID      `json:"deck_id"`
+	Remaining int            `json:"remaining"`
+	Hands     []PileResponse `json:"hands"`
+}
+
+func NewDealResponse(deck Deck, hands []string, dealt [][]Card) DealResponse {
+	response := DealResponse{
+		DeckID:    deck.ID,
+		Remaining: len(deck.Cards),
+		Hands:     make([]PileResponse, 0, len(hands)),
+	}
+	for i, hand := range hands {
+		response.Hands = append(response.Hands, NewPileResponse(deck.ID, hand, dealt[i]))
+	}
+	return response
+}
+
+func newPileNotFoundError(deckID uuid.UUID, name string) *pkg.NotFoundError {
+	return pkg.NewNotFoundError(fmt.Sprintf("pile %s of deck with ID %s not found", name, deckID))
+}
@@ -0,0 +1,244 @@
+package internal
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/prathoss/cards/pkg"
+)
+
+func TestDrawToPile(t *testing.T) {
+	deck, err := NewDeck(DeckOptions{Cards: []string{"AS", "KH", "2C", "3D"}}, CryptoRandomSource{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := deck.DrawToPile("discard", 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := deck.DrawToPile("discard", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	pile, err := deck.Pile("discard")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := cardsToCodes(pile), []string{"2C", "AS", "KH"}; !slices.Equal(got, want) {
+		t.Errorf("pile got %v, want %v", got, want)
+	}
+	if got, want := cardsToCodes(deck.Cards), []string{"3D"}; !slices.Equal(got, want) {
+		t.Errorf("deck got %v, want %v", got, want)
+	}
+	if err := deck.DrawToPile("discard", 2); err == nil {
+		t.Errorf("expected error when drawing more cards than available")
+	}
+}
+
+func TestDeal(t *testing.T) {
+	tests := []struct {
+		name      string
+		hands     []string
+		count     int
+		wantHands [][]string
+		wantDeck  []string
+		wantErr   bool
+	}{
+		{
+			name:      "RoundRobin",
+			hands:     []string{"alice", "bob"},
+			count:     2,
+			wantHands: [][]string{{"AS", "3S"}, {"2S", "4S"}},
+			wantDeck:  []string{"5S", "6S"},
+		},
+		{
+			name:      "SingleHand",
+			hands:     []string{"alice"},
+			count:     3,
+			wantHands: [][]string{{"AS", "2S", "3S"}},
+			wantDeck:  []string{"4S", "5S", "6S"},
+		},
+		{
+			name:      "AllCards",
+			hands:     []string{"alice", "bob", "carol"},
+			count:     2,
+			wantHands: [][]string{{"AS", "4S"}, {"2S", "5S"}, {"3S", "6S"}},
+			wantDeck:  []string{},
+		},
+		{
+			name:    "NotEnoughCards",
+			hands:   []string{"alice", "bob", "carol"},
+			count:   3,
+			wantErr: true,
+		},
+		{
+			name:    "OverflowingCount",
+			hands:   []string{"alice", "bob"},
+			count:   math.MaxInt/2 + 1,
+			wantErr: true,
+		},
+		{
+			name:    "NegativeCount",
+			hands:   []string{"alice", "bob"},
+			count:   -1,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deck := Deck{Cards: spades(6), Piles: map[string][]Card{"alice": {{Value: CardValueKing, Suit: CardSuitHearths}}}}
+
+			dealt, err := deck.Deal(tt.hands, tt.count)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Deck.Deal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if got := cardsToCodes(deck.Cards); len(got) != 6 {
+					t.Errorf("failed deal changed the deck to %v", got)
+				}
+				return
+			}
+
+			for i, hand := range tt.hands {
+				if got := cardsToCodes(dealt[i]); !slices.Equal(got, tt.wantHands[i]) {
+					t.Errorf("hand %s got %v, want %v", hand, got, tt.wantHands[i])
+				}
+				pile, err := deck.Pile(hand)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got := cardsToCodes(pile[:tt.count]); !slices.Equal(got, tt.wantHands[i]) {
+					t.Errorf("pile %s got %v, want %v on top", hand, got, tt.wantHands[i])
+				}
+			}
+			if got := cardsToCodes(deck.Piles["alice"]); got[len(got)-1] != "KH" {
+				t.Errorf("pile alice got %v, want KH kept at the bottom", got)
+			}
+			if got := cardsToCodes(deck.Cards); !slices.Equal(got, tt.wantDeck) {
+				t.Errorf("deck got %v, want %v", got, tt.wantDeck)
+			}
+		})
+	}
+}
+
+func TestDrawFromPile(t *testing.T) {
+	tests := []struct {
+		name     string
+		selector CardSelector
+		want     []string
+		wantPile []string
+		wantErr  bool
+	}{
+		{
+			name:     "Draw from top",
+			selector: CardSelector{Count: 2, Position: PositionTop},
+			want:     []string{"AS", "KH"},
+			wantPile: []string{"2C", "3D"},
+		},
+		{
+			name:     "Draw from bottom",
+			selector: CardSelector{Count: 2, Position: PositionBottom},
+			want:     []string{"2C", "3D"},
+			wantPile: []string{"AS", "KH"},
+		},
+		{
+			name:     "Draw specific cards",
+			selector: CardSelector{Codes: []string{"3D", "KH"}},
+			want:     []string{"3D", "KH"},
+			wantPile: []string{"AS", "2C"},
+		},
+		{
+			name:     "Draw cards not in pile",
+			selector: CardSelector{Codes: []string{"3D", "QH"}},
+			wantErr:  true,
+		},
+		{
+			name:     "Draw more cards than available",
+			selector: CardSelector{Count: 5, Position: PositionTop},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deck, err := NewDeck(DeckOptions{Cards: []string{"AS", "KH", "2C", "3D"}}, CryptoRandomSource{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := deck.DrawToPile("hand", 4); err != nil {
+				t.Fatal(err)
+			}
+
+			cards, err := deck.DrawFromPile("hand", tt.selector, CryptoRandomSource{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Deck.DrawFromPile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := cardsToCodes(cards); !slices.Equal(got, tt.want) {
+				t.Errorf("drawn got %v, want %v", got, tt.want)
+			}
+			if got := cardsToCodes(deck.Piles["hand"]); !slices.Equal(got, tt.wantPile) {
+				t.Errorf("pile got %v, want %v", got, tt.wantPile)
+			}
+		})
+	}
+}
+
+func TestPile_NotFound(t *testing.T) {
+	deck := Deck{}
+
+	_, err := deck.DrawFromPile("missing", CardSelector{Count: 1}, CryptoRandomSource{})
+	var notFoundError *pkg.NotFoundError
+	if !errors.As(err, &notFoundError) {
+		t.Errorf("expected NotFoundError, got %v", err)
+	}
+	if err := deck.ShufflePile("missing", CryptoRandomSource{}); !errors.As(err, &notFoundError) {
+		t.Errorf("expected NotFoundError, got %v", err)
+	}
+}
+
+func TestShufflePile(t *testing.T) {
+	deck, err := NewDeck(DeckOptions{}, CryptoRandomSource{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := deck.DrawToPile("discard", 10); err != nil {
+		t.Fatal(err)
+	}
+	before := cardsToCodes(deck.Piles["discard"])
+
+	if err := deck.ShufflePile("discard", CryptoRandomSource{}); err != nil {
+		t.Fatal(err)
+	}
+
+	after := cardsToCodes(deck.Piles["discard"])
+	slices.Sort(before)
+	slices.Sort(after)
+	if !slices.Equal(before, after) {
+		t.Errorf("shuffled pile has different cards, got %v, want %v", after, before)
+	}
+}
+
+func TestReturnCards_CardInPile(t *testing.T) {
+	deck, err := NewDeck(DeckOptions{Cards: []string{"AS", "KH"}}, CryptoRandomSource{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := deck.DrawToPile("hand", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := deck.ReturnCards([]string{"AS"}, PositionTop, CryptoRandomSource{}); err == nil {
+		t.Errorf("expected error when returning card held in a pile")
+	}
+
+	deck.ReturnDrawnCards()
+	if got, want := cardsToCodes(deck.Cards), []string{"KH"}; !slices.Equal(got, want) {
+		t.Errorf("cards in piles should not be returned, got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,74 @@
+package internal
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	rand2 "math/rand/v2"
+	"sync"
+)
+
+const (
+	RandomSourceCrypto = "crypto"
+	RandomSourceSeeded = "seeded"
+)
+
+// RandomSource provides randomness to every shuffle and random draw
+type RandomSource interface {
+	// IntN returns a uniformly distributed random number in [0, n)
+	IntN(n int) (int, error)
+}
+
+var _ RandomSource = CryptoRandomSource{}
+
+// CryptoRandomSource draws numbers from the cryptographically secure crypto/rand.Reader
+type CryptoRandomSource struct{}
+
+func (CryptoRandomSource) IntN(n int) (int, error) {
+	i, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, err
+	}
+	return int(i.Int64()), nil
+}
+
+var _ RandomSource = (*SeededRandomSource)(nil)
+
+// SeededRandomSource is a deterministic source of random numbers, safe for concurrent use.
+// The numbers come from PCG-DXSM (math/rand/v2 NewPCG) initialized with seed and stream,
+// a number in [0, n) is picked by rejecting Uint64 values below 2^64 mod n and taking the remainder of the rest.
+// The same seed and stream always produce the same sequence, which allows replaying shuffles.
+type SeededRandomSource struct {
+	mu  sync.Mutex
+	pcg *rand2.PCG
+}
+
+func NewSeededRandomSource(seed, stream uint64) *SeededRandomSource {
+	return &SeededRandomSource{
+		pcg: rand2.NewPCG(seed, stream),
+	}
+}
+
+func (s *SeededRandomSource) IntN(n int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	bound := uint64(n)
+	threshold := -bound % bound
+	for {
+		if v := s.pcg.Uint64(); v >= threshold {
+			return int(v % bound), nil
+		}
+	}
+}
+
+// NewRandomSource returns the source of randomness selected by the configuration
+func NewRandomSource(config Config) (RandomSource, error) {
+	switch config.RandomSource {
+	case RandomSourceCrypto, "":
+		return CryptoRandomSource{}, nil
+	case RandomSourceSeeded:
+		return NewSeededRandomSource(config.RandomSeed, 0), nil
+	default:
+		return nil, fmt.Errorf("unknown random source %q", config.RandomSource)
+	}
+}
@@ -0,0 +1,171 @@
+package internal
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+)
+
+// ShuffleSelfTestOptions configure RunShuffleSelfTest
+type ShuffleSelfTestOptions struct {
+	// Deck is shuffled again and again from its initial order, its cards must have unique codes
+	Deck DeckOptions
+	// Shuffles is the number of shuffles to sample
+	Shuffles int
+}
+
+// ShuffleSelfTestResult holds statistics of sampled shuffles
+type ShuffleSelfTestResult struct {
+	Shuffles int
+	// Position tests every card is equally likely to end up at every position
+	Position ChiSquareResult
+	// Adjacency tests every card is equally likely to follow every other card
+	Adjacency ChiSquareResult
+}
+
+// Passed reports whether both statistics are within threshold
+func (r ShuffleSelfTestResult) Passed(threshold float64) bool {
+	return r.Position.Passed(threshold) && r.Adjacency.Passed(threshold)
+}
+
+// ChiSquareResult is a chi-square statistic with its degrees of freedom
+// and its z-score given by the Wilson-Hilferty approximation
+type ChiSquareResult struct {
+	Statistic        float64
+	DegreesOfFreedom int
+	ZScore           float64
+}
+
+// Passed reports whether the absolute z-score is within threshold.
+// Distributions fitting too well are rejected as well, they indicate shuffles are not independent.
+func (c ChiSquareResult) Passed(threshold float64) bool {
+	return math.Abs(c.ZScore) <= threshold
+}
+
+func newChiSquareResult(statistic float64, degreesOfFreedom int) ChiSquareResult {
+	k := float64(degreesOfFreedom)
+	variance := 2 / (9 * k)
+	return ChiSquareResult{
+		Statistic:        statistic,
+		DegreesOfFreedom: degreesOfFreedom,
+		ZScore:           (math.Cbrt(statistic/k) - (1 - variance)) / math.Sqrt(variance),
+	}
+}
+
+// RunShuffleSelfTest shuffles the deck with Deck.ShuffleCards options.Shuffles times
+// and computes chi-square statistics of the card by position and card by following card counts
+func RunShuffleSelfTest(options ShuffleSelfTestOptions, random RandomSource) (ShuffleSelfTestResult, error) {
+	if options.Shuffles < 1 {
+		return ShuffleSelfTestResult{}, errors.New("at least one shuffle is needed")
+	}
+	deck, err := NewDeck(options.Deck, random)
+	if err != nil {
+		return ShuffleSelfTestResult{}, err
+	}
+	initial := slices.Clone(deck.Cards)
+	n := len(initial)
+	if n < 3 {
+		return ShuffleSelfTestResult{}, errors.New("at least three cards are needed")
+	}
+	indexes := make(map[string]int, n)
+	for i, card := range initial {
+		indexes[card.Code()] = i
+	}
+	if len(indexes) != n {
+		return ShuffleSelfTestResult{}, errors.New("cards of the deck should have unique codes")
+	}
+
+	positions := make([]int, n*n)
+	adjacency := make([]int, n*n)
+	for range options.Shuffles {
+		deck.Cards = append(deck.Cards[:0], initial...)
+		if err := deck.ShuffleCards(random); err != nil {
+			return ShuffleSelfTestResult{}, err
+		}
+		previous := -1
+		for position, card := range deck.Cards {
+			index := indexes[card.Code()]
+			positions[index*n+position]++
+			if previous >= 0 {
+				adjacency[previous*n+index]++
+			}
+			previous = index
+		}
+	}
+
+	// each card is at each position in 1/n of shuffles,
+	// each of n-1 pairs of a shuffle is one of n*(n-1) ordered pairs of distinct cards, so the expected counts are equal
+	expected := float64(options.Shuffles) / float64(n)
+	var positionStatistic float64
+	for _, observed := range positions {
+		positionStatistic += math.Pow(float64(observed)-expected, 2) / expected
+	}
+
+	var adjacencyStatistic float64
+	for i := range n {
+		for j := range n {
+			if i != j {
+				adjacencyStatistic += math.Pow(float64(adjacency[i*n+j])-expected, 2) / expected
+			}
+		}
+	}
+
+	// every count is the number of shuffles with an event of probability 1/n, so each contributes 1-1/n
+	// to the expected statistic, which is taken as its degrees of freedom
+	return ShuffleSelfTestResult{
+		Shuffles:  options.Shuffles,
+		Position:  newChiSquareResult(positionStatistic, n*(n-1)),
+		Adjacency: newChiSquareResult(adjacencyStatistic, (n-1)*(n-1)),
+	}, nil
+}
+
+// SelfTest runs the self-test command given by args (e.g. "shuffle -shuffles 100000") and writes its report to w.
+// It fails when a statistic exceeds the threshold.
+func SelfTest(args []string, w io.Writer) error {
+	if len(args) == 0 || args[0] != "shuffle" {
+		return errors.New(`usage: selftest shuffle [flags], "shuffle" is the only self-test`)
+	}
+
+	flags := flag.NewFlagSet("selftest shuffle", flag.ContinueOnError)
+	flags.SetOutput(w)
+	shuffles := flags.Int("shuffles", 100000, "number of shuffles to sample")
+	threshold := flags.Float64("threshold", 4, "largest accepted absolute z-score")
+	source := flags.String("source", RandomSourceCrypto, "source of randomness, crypto or seeded")
+	seed := flags.Uint64("seed", 0, "seed of the seeded source of randomness")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	random, err := NewRandomSource(Config{RandomSource: *source, RandomSeed: *seed})
+	if err != nil {
+		return err
+	}
+	result, err := RunShuffleSelfTest(ShuffleSelfTestOptions{Shuffles: *shuffles}, random)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "shuffles: %d, threshold: %.2f\n", result.Shuffles, *threshold)
+	for _, statistic := range []struct {
+		name   string
+		result ChiSquareResult
+	}{
+		{name: "position", result: result.Position},
+		{name: "adjacency", result: result.Adjacency},
+	} {
+		verdict := "ok"
+		if !statistic.result.Passed(*threshold) {
+			verdict = "BIASED"
+		}
+		fmt.Fprintf(w, "%-10s chi2 = %.2f, df = %d, z = %.2f: %s\n",
+			statistic.name, statistic.result.Statistic, statistic.result.DegreesOfFreedom, statistic.result.ZScore, verdict)
+	}
+
+	if !result.Passed(*threshold) {
+		return errors.New("shuffle is biased")
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package internal
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRunShuffleSelfTest(t *testing.T) {
+	tests := []struct {
+		name       string
+		random     RandomSource
+		wantPassed bool
+	}{
+		{
+			name:       "Unbiased",
+			random:     NewSeededRandomSource(1, 0),
+			wantPassed: true,
+		},
+		{
+			name:       "Biased",
+			random:     NewFixtureRandomSource(0),
+			wantPassed: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := RunShuffleSelfTest(ShuffleSelfTestOptions{
+				Deck:     DeckOptions{Cards: []string{"AS", "2S", "3S", "4S", "5S", "6S"}},
+				Shuffles: 5000,
+			}, tt.random)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := result.Passed(4); got != tt.wantPassed {
+				t.Errorf("Passed() = %v, want %v, result = %+v", got, tt.wantPassed, result)
+			}
+		})
+	}
+}
+
+func TestRunShuffleSelfTest_DuplicateCards(t *testing.T) {
+	_, err := RunShuffleSelfTest(ShuffleSelfTestOptions{
+		Deck:     DeckOptions{Decks: 2},
+		Shuffles: 1,
+	}, CryptoRandomSource{})
+	if err == nil {
+		t.Errorf("expected error for a deck with duplicate cards")
+	}
+}
+
+func TestSelfTest(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr bool
+	}{
+		{
+			name: "Shuffle",
+			args: []string{"shuffle", "-shuffles", "2000", "-source", "seeded", "-seed", "7"},
+		},
+		{
+			name:    "MissingTest",
+			args:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "UnknownTest",
+			args:    []string{"draw"},
+			wantErr: true,
+		},
+		{
+			name:    "UnknownSource",
+			args:    []string{"shuffle", "-source", "dice"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := SelfTest(tt.args, &out)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SelfTest() error = %v, wantErr %v, output:\n%s", err, tt.wantErr, out.String())
+			}
+			if !tt.wantErr && !strings.Contains(out.String(), "adjacency") {
+				t.Errorf("SelfTest() output does not report adjacency:\n%s", out.String())
+			}
+		})
+	}
+}
@@ -2,12 +2,12 @@ package internal
 
 import (
 	"context"
+	"crypto/subtle"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -16,50 +16,535 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// sweepInterval is how often the memory storage deletes expired decks
+const sweepInterval = time.Minute
+
 type Server struct {
-	config            Config
-	deckProcessor     DeckProcessor
-	drawingCardsMutex sync.Mutex
+	config        Config
+	deckProcessor DeckProcessor
+}
+
+func NewServer(config Config) (*Server, error) {
+	deckProcessor, err := newDeckProcessor(config)
+	if err != nil {
+		return nil, err
+	}
+	return &Server{
+		config:        config,
+		deckProcessor: deckProcessor,
+	}, nil
+}
+
+func newDeckProcessor(config Config) (DeckProcessor, error) {
+	random, err := NewRandomSource(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.Storage == StorageMemory {
+		return NewMemoryDeckProcessor(random), nil
+	}
+
+	ctx, cFunc := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cFunc()
+
+	serverAPI := options.ServerAPI(options.ServerAPIVersion1)
+	opts := options.Client().ApplyURI(config.MongoConnection).SetServerAPIOptions(serverAPI)
+	client, err := mongo.Connect(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	repository := NewDeckRepository(client, random)
+	if err := repository.CreateIndexes(ctx); err != nil {
+		return nil, err
+	}
+	return repository, nil
+}
+
+func (s *Server) createDeck(w http.ResponseWriter, r *http.Request) (any, error) {
+	var invalidParams []pkg.InvalidParam
+
+	shuffled, shuffledErrors := parseBool(r, "shuffled")
+	invalidParams = append(invalidParams, shuffledErrors...)
+
+	jokers, jokersErrors := parseBool(r, "jokers")
+	invalidParams = append(invalidParams, jokersErrors...)
+
+	decks, decksErrors := parseDecks(r)
+	invalidParams = append(invalidParams, decksErrors...)
+
+	template, typeErrors := parseDeckType(r)
+	invalidParams = append(invalidParams, typeErrors...)
+
+	cards, cardsErrors := parseCards(r, template)
+	invalidParams = append(invalidParams, cardsErrors...)
+
+	shuffleMethod, shuffleMethodErrors := parseShuffleMethod(r)
+	invalidParams = append(invalidParams, shuffleMethodErrors...)
+
+	seed, seedErrors := parseSeed(r)
+	invalidParams = append(invalidParams, seedErrors...)
+
+	fair, fairErrors := parseBool(r, "fair")
+	invalidParams = append(invalidParams, fairErrors...)
+	if fair && seed != nil {
+		invalidParams = append(invalidParams, pkg.InvalidParam{
+			Name:   "seed",
+			Reason: "provably fair deck can not be seeded",
+		})
+	}
+	if fair && shuffleMethod != (ShuffleMethod{}) {
+		invalidParams = append(invalidParams, pkg.InvalidParam{
+			Name:   "mode",
+			Reason: "provably fair deck is always shuffled randomly",
+		})
+	}
+
+	// the client seed is accepted only after the hash of the server seed is published, see Deck.SeedFairly
+	if r.URL.Query().Has("client_seed") {
+		invalidParams = append(invalidParams, pkg.InvalidParam{
+			Name:   "client_seed",
+			Reason: "client seed is sent to POST /api/v1/deck/{id}/seed after the deck is created",
+		})
+	}
+
+	sealed, sealedErrors := parseBool(r, "sealed")
+	invalidParams = append(invalidParams, sealedErrors...)
+
+	maxPeek, maxPeekErrors := parseMaxPeek(r, sealed)
+	invalidParams = append(invalidParams, maxPeekErrors...)
+
+	hidden, hiddenErrors := parseBool(r, "hidden")
+	invalidParams = append(invalidParams, hiddenErrors...)
+
+	if len(invalidParams) > 0 {
+		return nil, pkg.NewBadRequestError(invalidParams...)
+	}
+
+	var ownerToken string
+	if hidden {
+		var err error
+		ownerToken, err = NewOwnerToken()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	deck, err := s.deckProcessor.Create(r.Context(), DeckOptions{
+		Type:  template.Name,
+		Cards: cards,
+		// choosing a shuffle mode implies the deck is shuffled
+		Shuffled:      shuffled || shuffleMethod != (ShuffleMethod{}),
+		ShuffleMethod: shuffleMethod,
+		Jokers:        jokers,
+		Decks:         decks,
+		Seed:          seed,
+		Fair:          fair,
+		Sealed:        sealed,
+		MaxPeek:       maxPeek,
+		Hidden:        hidden,
+		OwnerToken:    ownerToken,
+		TTL:           s.config.DeckTTL,
+		UndoDepth:     s.config.UndoDepth,
+	})
+	if err != nil {
+		return nil, err
+	}
+	setETag(w, deck)
+	response := s.newCreateDeckResponse(r, deck)
+	response.OwnerToken = ownerToken
+	return response, nil
+}
+
+func (s *Server) openDeck(w http.ResponseWriter, r *http.Request) (any, error) {
+	var invalidParams []pkg.InvalidParam
+
+	id, idErrors := parseID(r)
+	invalidParams = append(invalidParams, idErrors...)
+
+	if len(invalidParams) > 0 {
+		return nil, pkg.NewBadRequestError(invalidParams...)
+	}
+
+	deck, err := s.deckProcessor.Get(r.Context(), id)
+	if err != nil {
+		return nil, err
+	}
+	if err := deck.CheckCanOpen(); err != nil {
+		return nil, err
+	}
+	setETag(w, deck)
+	if deck.Hidden && !deck.IsOwner(bearerToken(r)) {
+		return NewHiddenDeckResponse(deck), nil
+	}
+	response := NewOpenDeckResponse(deck)
+	if s.isAdmin(r) {
+		response.Seed = deck.Seed
+	}
+	return response, nil
+}
+
+func (s *Server) deckStats(_ http.ResponseWriter, r *http.Request) (any, error) {
+	var invalidParams []pkg.InvalidParam
+
+	id, idErrors := parseID(r)
+	invalidParams = append(invalidParams, idErrors...)
+
+	predicates, predicateErrors := parseCardPredicates(r)
+	invalidParams = append(invalidParams, predicateErrors...)
+
+	if len(invalidParams) > 0 {
+		return nil, pkg.NewBadRequestError(invalidParams...)
+	}
+
+	deck, err := s.deckProcessor.Get(r.Context(), id)
+	if err != nil {
+		return nil, err
+	}
+	return NewStatsResponse(deck, predicates), nil
+}
+
+func (s *Server) peekCards(_ http.ResponseWriter, r *http.Request) (any, error) {
+	var invalidParams []pkg.InvalidParam
+
+	id, idErrors := parseID(r)
+	invalidParams = append(invalidParams, idErrors...)
+
+	count, countErrors := parseCount(r)
+	invalidParams = append(invalidParams, countErrors...)
+
+	position, positionErrors := parsePosition(r)
+	invalidParams = append(invalidParams, positionErrors...)
+
+	if len(invalidParams) > 0 {
+		return nil, pkg.NewBadRequestError(invalidParams...)
+	}
+
+	deck, err := s.deckProcessor.Get(r.Context(), id)
+	if err != nil {
+		return nil, err
+	}
+	if err := deck.CheckCanReveal(bearerToken(r)); err != nil {
+		return nil, err
+	}
+	cards, err := deck.Peek(count, position)
+	if err != nil {
+		return nil, err
+	}
+	return NewCardsResponse(cards), nil
+}
+
+func (s *Server) drawCards(w http.ResponseWriter, r *http.Request) (any, error) {
+	var invalidParams []pkg.InvalidParam
+
+	id, idErrors := parseID(r)
+	invalidParams = append(invalidParams, idErrors...)
+
+	selector, selectorErrors := parseCardSelector(r)
+	invalidParams = append(invalidParams, selectorErrors...)
+
+	if len(invalidParams) > 0 {
+		return nil, pkg.NewBadRequestError(invalidParams...)
+	}
+
+	deck, cards, err := s.deckProcessor.DrawCards(ifMatchContext(r), id, selector)
+	if err != nil {
+		return nil, err
+	}
+	setETag(w, deck)
+	return NewCardsResponse(cards), nil
+}
+
+func (s *Server) returnCards(w http.ResponseWriter, r *http.Request) (any, error) {
+	var invalidParams []pkg.InvalidParam
+
+	id, idErrors := parseID(r)
+	invalidParams = append(invalidParams, idErrors...)
+
+	codes, codesErrors := parseCardCodes(r)
+	invalidParams = append(invalidParams, codesErrors...)
+
+	position, positionErrors := parsePosition(r)
+	invalidParams = append(invalidParams, positionErrors...)
+
+	if len(invalidParams) > 0 {
+		return nil, pkg.NewBadRequestError(invalidParams...)
+	}
+
+	deck, err := s.deckProcessor.ReturnCards(ifMatchContext(r), id, codes, position)
+	if err != nil {
+		return nil, err
+	}
+	setETag(w, deck)
+	return s.newCreateDeckResponse(r, deck), nil
+}
+
+func (s *Server) shuffleDeck(w http.ResponseWriter, r *http.Request) (any, error) {
+	var invalidParams []pkg.InvalidParam
+
+	id, idErrors := parseID(r)
+	invalidParams = append(invalidParams, idErrors...)
+
+	returnDrawn, returnErrors := parseBool(r, "return")
+	invalidParams = append(invalidParams, returnErrors...)
+
+	seed, seedErrors := parseSeed(r)
+	invalidParams = append(invalidParams, seedErrors...)
+
+	shuffleMethod, shuffleMethodErrors := parseShuffleMethod(r)
+	invalidParams = append(invalidParams, shuffleMethodErrors...)
+
+	if len(invalidParams) > 0 {
+		return nil, pkg.NewBadRequestError(invalidParams...)
+	}
+
+	deck, err := s.deckProcessor.Shuffle(ifMatchContext(r), id, ShuffleOptions{
+		ReturnDrawn: returnDrawn,
+		Seed:        seed,
+		Method:      shuffleMethod,
+	})
+	if err != nil {
+		return nil, err
+	}
+	setETag(w, deck)
+	return s.newCreateDeckResponse(r, deck), nil
+}
+
+func (s *Server) cutDeck(w http.ResponseWriter, r *http.Request) (any, error) {
+	var invalidParams []pkg.InvalidParam
+
+	id, idErrors := parseID(r)
+	invalidParams = append(invalidParams, idErrors...)
+
+	options, cutErrors := parseCutOptions(r)
+	invalidParams = append(invalidParams, cutErrors...)
+
+	reveal, revealErrors := parseBool(r, "reveal")
+	invalidParams = append(invalidParams, revealErrors...)
+
+	if len(invalidParams) > 0 {
+		return nil, pkg.NewBadRequestError(invalidParams...)
+	}
+
+	if reveal {
+		// checked before cutting, so a forbidden reveal does not modify the deck
+		deck, err := s.deckProcessor.Get(r.Context(), id)
+		if err != nil {
+			return nil, err
+		}
+		if err := deck.CheckCanReveal(bearerToken(r)); err != nil {
+			return nil, err
+		}
+	}
+	deck, position, err := s.deckProcessor.Cut(ifMatchContext(r), id, options)
+	if err != nil {
+		return nil, err
+	}
+	setETag(w, deck)
+	response := CutResponse{
+		CreateDeckResponse: s.newCreateDeckResponse(r, deck),
+		Position:           position,
+	}
+	if reveal {
+		card := NewCardResponse(deck.Cards[0])
+		response.Card = &card
+	}
+	return response, nil
+}
+
+func (s *Server) drawToPile(w http.ResponseWriter, r *http.Request) (any, error) {
+	var invalidParams []pkg.InvalidParam
+
+	id, idErrors := parseID(r)
+	invalidParams = append(invalidParams, idErrors...)
+
+	pile, pileErrors := parsePileName(r)
+	invalidParams = append(invalidParams, pileErrors...)
+
+	count, countErrors := parseCount(r)
+	invalidParams = append(invalidParams, countErrors...)
+
+	if len(invalidParams) > 0 {
+		return nil, pkg.NewBadRequestError(invalidParams...)
+	}
+
+	deck, err := s.deckProcessor.DrawToPile(ifMatchContext(r), id, pile, count)
+	if err != nil {
+		return nil, err
+	}
+	setETag(w, deck)
+	return s.newPileResponse(r, deck, pile), nil
+}
+
+func (s *Server) deal(w http.ResponseWriter, r *http.Request) (any, error) {
+	var invalidParams []pkg.InvalidParam
+
+	id, idErrors := parseID(r)
+	invalidParams = append(invalidParams, idErrors...)
+
+	hands, handsErrors := parseHands(r)
+	invalidParams = append(invalidParams, handsErrors...)
+
+	count, countErrors := parseCount(r)
+	invalidParams = append(invalidParams, countErrors...)
+
+	if len(invalidParams) > 0 {
+		return nil, pkg.NewBadRequestError(invalidParams...)
+	}
+
+	deck, dealt, err := s.deckProcessor.Deal(ifMatchContext(r), id, hands, count)
+	if err != nil {
+		return nil, err
+	}
+	setETag(w, deck)
+	return NewDealResponse(deck, hands, dealt), nil
+}
+
+func (s *Server) listPile(_ http.ResponseWriter, r *http.Request) (any, error) {
+	var invalidParams []pkg.InvalidParam
+
+	id, idErrors := parseID(r)
+	invalidParams = append(invalidParams, idErrors...)
+
+	pile, pileErrors := parsePileName(r)
+	invalidParams = append(invalidParams, pileErrors...)
+
+	if len(invalidParams) > 0 {
+		return nil, pkg.NewBadRequestError(invalidParams...)
+	}
+
+	deck, err := s.deckProcessor.Get(r.Context(), id)
+	if err != nil {
+		return nil, err
+	}
+	if err := deck.CheckCanReveal(bearerToken(r)); err != nil {
+		return nil, err
+	}
+	cards, err := deck.Pile(pile)
+	if err != nil {
+		return nil, err
+	}
+	return NewPileResponse(deck.ID, pile, cards), nil
+}
+
+func (s *Server) drawFromPile(w http.ResponseWriter, r *http.Request) (any, error) {
+	var invalidParams []pkg.InvalidParam
+
+	id, idErrors := parseID(r)
+	invalidParams = append(invalidParams, idErrors...)
+
+	pile, pileErrors := parsePileName(r)
+	invalidParams = append(invalidParams, pileErrors...)
+
+	selector, selectorErrors := parseCardSelector(r)
+	invalidParams = append(invalidParams, selectorErrors...)
+
+	if len(invalidParams) > 0 {
+		return nil, pkg.NewBadRequestError(invalidParams...)
+	}
+
+	deck, cards, err := s.deckProcessor.DrawFromPile(ifMatchContext(r), id, pile, selector)
+	if err != nil {
+		return nil, err
+	}
+	setETag(w, deck)
+	return NewCardsResponse(cards), nil
+}
+
+func (s *Server) shufflePile(w http.ResponseWriter, r *http.Request) (any, error) {
+	var invalidParams []pkg.InvalidParam
+
+	id, idErrors := parseID(r)
+	invalidParams = append(invalidParams, idErrors...)
+
+	pile, pileErrors := parsePileName(r)
+	invalidParams = append(invalidParams, pileErrors...)
+
+	if len(invalidParams) > 0 {
+		return nil, pkg.NewBadRequestError(invalidParams...)
+	}
+
+	deck, err := s.deckProcessor.ShufflePile(ifMatchContext(r), id, pile)
+	if err != nil {
+		return nil, err
+	}
+	setETag(w, deck)
+	return s.newPileResponse(r, deck, pile), nil
+}
+
+func (s *Server) createDeckClone(w http.ResponseWriter, r *http.Request) (any, error) {
+	var invalidParams []pkg.InvalidParam
+
+	id, idErrors := parseID(r)
+	invalidParams = append(invalidParams, idErrors...)
+
+	if len(invalidParams) > 0 {
+		return nil, pkg.NewBadRequestError(invalidParams...)
+	}
+
+	parent, err := s.deckProcessor.Get(r.Context(), id)
+	if err != nil {
+		return nil, err
+	}
+	if err := parent.CheckCanClone(bearerToken(r)); err != nil {
+		return nil, err
+	}
+	deck, err := s.deckProcessor.Clone(r.Context(), id)
+	if err != nil {
+		return nil, err
+	}
+	setETag(w, deck)
+	return s.newCreateDeckResponse(r, deck), nil
 }
 
-func NewServer(config Config) (*Server, error) {
-	ctx, cFunc := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cFunc()
+func (s *Server) undo(w http.ResponseWriter, r *http.Request) (any, error) {
+	var invalidParams []pkg.InvalidParam
 
-	serverAPI := options.ServerAPI(options.ServerAPIVersion1)
-	opts := options.Client().ApplyURI(config.MongoConnection).SetServerAPIOptions(serverAPI)
-	client, err := mongo.Connect(ctx, opts)
+	id, idErrors := parseID(r)
+	invalidParams = append(invalidParams, idErrors...)
+
+	if len(invalidParams) > 0 {
+		return nil, pkg.NewBadRequestError(invalidParams...)
+	}
+
+	// the restored cards were seen by whoever drew them, the order of a hidden deck must stay unknown to others
+	current, err := s.deckProcessor.Get(r.Context(), id)
 	if err != nil {
 		return nil, err
 	}
-	return &Server{
-		config:            config,
-		deckProcessor:     NewDeckRepository(client),
-		drawingCardsMutex: sync.Mutex{},
-	}, nil
+	if err := current.CheckCanReveal(bearerToken(r)); err != nil {
+		return nil, err
+	}
+	deck, err := s.deckProcessor.Undo(ifMatchContext(r), id)
+	if err != nil {
+		return nil, err
+	}
+	setETag(w, deck)
+	return s.newCreateDeckResponse(r, deck), nil
 }
 
-func (s *Server) createDeck(_ http.ResponseWriter, r *http.Request) (any, error) {
+func (s *Server) seedDeck(w http.ResponseWriter, r *http.Request) (any, error) {
 	var invalidParams []pkg.InvalidParam
 
-	shuffled, shuffledErrors := parseShuffled(r)
-	invalidParams = append(invalidParams, shuffledErrors...)
+	id, idErrors := parseID(r)
+	invalidParams = append(invalidParams, idErrors...)
 
-	cards, cardsErrors := parseCards(r)
-	invalidParams = append(invalidParams, cardsErrors...)
+	clientSeed, clientSeedErrors := parseClientSeed(r)
+	invalidParams = append(invalidParams, clientSeedErrors...)
 
 	if len(invalidParams) > 0 {
 		return nil, pkg.NewBadRequestError(invalidParams...)
 	}
 
-	deck, err := s.deckProcessor.Create(r.Context(), cards, shuffled)
+	deck, err := s.deckProcessor.SeedFairness(ifMatchContext(r), id, clientSeed)
 	if err != nil {
 		return nil, err
 	}
-	return NewCreateDeckResponse(deck), nil
+	setETag(w, deck)
+	return s.newCreateDeckResponse(r, deck), nil
 }
 
-func (s *Server) openDeck(_ http.ResponseWriter, r *http.Request) (any, error) {
+func (s *Server) closeDeck(w http.ResponseWriter, r *http.Request) (any, error) {
 	var invalidParams []pkg.InvalidParam
 
 	id, idErrors := parseID(r)
@@ -69,42 +554,154 @@ func (s *Server) openDeck(_ http.ResponseWriter, r *http.Request) (any, error) {
 		return nil, pkg.NewBadRequestError(invalidParams...)
 	}
 
-	deck, err := s.deckProcessor.Get(r.Context(), id)
+	deck, err := s.deckProcessor.Close(ifMatchContext(r), id)
 	if err != nil {
 		return nil, err
 	}
-	return NewOpenDeckResponse(deck), nil
+	setETag(w, deck)
+	return s.newCreateDeckResponse(r, deck), nil
 }
 
-func (s *Server) drawCards(_ http.ResponseWriter, r *http.Request) (any, error) {
+func (s *Server) deleteDeck(_ http.ResponseWriter, r *http.Request) (any, error) {
 	var invalidParams []pkg.InvalidParam
 
 	id, idErrors := parseID(r)
 	invalidParams = append(invalidParams, idErrors...)
 
-	count, countErrors := parseCount(r)
-	invalidParams = append(invalidParams, countErrors...)
+	if len(invalidParams) > 0 {
+		return nil, pkg.NewBadRequestError(invalidParams...)
+	}
+
+	return nil, s.deckProcessor.Delete(ifMatchContext(r), id)
+}
+
+func (s *Server) deckHistory(_ http.ResponseWriter, r *http.Request) (any, error) {
+	var invalidParams []pkg.InvalidParam
+
+	id, idErrors := parseID(r)
+	invalidParams = append(invalidParams, idErrors...)
+
+	after, limit, pageErrors := parseHistoryPage(r)
+	invalidParams = append(invalidParams, pageErrors...)
 
 	if len(invalidParams) > 0 {
 		return nil, pkg.NewBadRequestError(invalidParams...)
 	}
 
-	s.drawingCardsMutex.Lock()
-	defer s.drawingCardsMutex.Unlock()
+	deck, err := s.deckProcessor.Get(r.Context(), id)
+	if err != nil {
+		return nil, err
+	}
+	if err := deck.CheckCanReveal(bearerToken(r)); err != nil {
+		return nil, err
+	}
+	events, err := s.deckProcessor.History(r.Context(), id, after, limit)
+	if err != nil {
+		return nil, err
+	}
+	return NewHistoryResponse(id, events, limit), nil
+}
+
+func (s *Server) verifyDeck(_ http.ResponseWriter, r *http.Request) (any, error) {
+	var invalidParams []pkg.InvalidParam
+
+	id, idErrors := parseID(r)
+	invalidParams = append(invalidParams, idErrors...)
+
+	if len(invalidParams) > 0 {
+		return nil, pkg.NewBadRequestError(invalidParams...)
+	}
 
-	cards, err := s.deckProcessor.DrawCards(r.Context(), id, count)
+	deck, err := s.deckProcessor.Get(r.Context(), id)
 	if err != nil {
 		return nil, err
 	}
-	return NewCardsResponse(cards), nil
+	if err := deck.CheckCanReveal(bearerToken(r)); err != nil {
+		return nil, err
+	}
+	return deck.VerifyFairness()
+}
+
+// newCreateDeckResponse returns summary of the deck, the seed is included only for administrators
+// and the revealed fairness of a hidden deck only for its owner
+func (s *Server) newCreateDeckResponse(r *http.Request, deck Deck) CreateDeckResponse {
+	response := NewCreateDeckResponse(deck)
+	if s.isAdmin(r) {
+		response.Seed = deck.Seed
+	}
+	if deck.CheckCanReveal(bearerToken(r)) != nil {
+		response.Fairness.conceal()
+	}
+	return response
+}
+
+// newPileResponse returns the pile of the modified deck, cards of a hidden deck are included only for its owner
+func (s *Server) newPileResponse(r *http.Request, deck Deck, pile string) PileResponse {
+	response := NewPileResponse(deck.ID, pile, deck.Piles[pile])
+	if deck.CheckCanReveal(bearerToken(r)) != nil {
+		response.Cards = nil
+	}
+	return response
+}
+
+// isAdmin reports whether the request carries the administrator token as bearer token
+func (s *Server) isAdmin(r *http.Request) bool {
+	if s.config.AdminToken == "" {
+		return false
+	}
+	token := bearerToken(r)
+	if token == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(s.config.AdminToken)) == 1
+}
+
+// setETag sets the ETag header to the entity tag of the deck, which can be sent back in If-Match of modifications
+func setETag(w http.ResponseWriter, deck Deck) {
+	w.Header().Set("ETag", DeckETag(deck))
+}
+
+// ifMatchContext returns the context of the request restricting modifications to versions listed by its If-Match header
+func ifMatchContext(r *http.Request) context.Context {
+	versions, ok := ParseIfMatch(r.Header.Get("If-Match"))
+	if !ok {
+		return r.Context()
+	}
+	return WithIfMatch(r.Context(), versions)
+}
+
+// bearerToken returns the token of the Authorization header, empty when the header is not a bearer token
+func bearerToken(r *http.Request) string {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return ""
+	}
+	return token
 }
 
 func (s *Server) Run() {
 	mux := http.NewServeMux()
 
 	mux.Handle("POST /api/v1/deck", pkg.HttpHandler(s.createDeck))
+	mux.Handle("DELETE /api/v1/deck/{id}", pkg.HttpHandler(s.deleteDeck))
 	mux.Handle("POST /api/v1/deck/{id}/open", pkg.HttpHandler(s.openDeck))
+	mux.Handle("POST /api/v1/deck/{id}/clone", pkg.HttpHandler(s.createDeckClone))
+	mux.Handle("GET /api/v1/deck/{id}/stats", pkg.HttpHandler(s.deckStats))
+	mux.Handle("GET /api/v1/deck/{id}/peek", pkg.HttpHandler(s.peekCards))
+	mux.Handle("GET /api/v1/deck/{id}/history", pkg.HttpHandler(s.deckHistory))
 	mux.Handle("POST /api/v1/deck/{id}/draw", pkg.HttpHandler(s.drawCards))
+	mux.Handle("POST /api/v1/deck/{id}/return", pkg.HttpHandler(s.returnCards))
+	mux.Handle("POST /api/v1/deck/{id}/shuffle", pkg.HttpHandler(s.shuffleDeck))
+	mux.Handle("POST /api/v1/deck/{id}/cut", pkg.HttpHandler(s.cutDeck))
+	mux.Handle("POST /api/v1/deck/{id}/undo", pkg.HttpHandler(s.undo))
+	mux.Handle("POST /api/v1/deck/{id}/seed", pkg.HttpHandler(s.seedDeck))
+	mux.Handle("POST /api/v1/deck/{id}/close", pkg.HttpHandler(s.closeDeck))
+	mux.Handle("POST /api/v1/deck/{id}/verify", pkg.HttpHandler(s.verifyDeck))
+	mux.Handle("POST /api/v1/deck/{id}/deal", pkg.HttpHandler(s.deal))
+	mux.Handle("GET /api/v1/deck/{id}/pile/{name}", pkg.HttpHandler(s.listPile))
+	mux.Handle("POST /api/v1/deck/{id}/pile/{name}/add", pkg.HttpHandler(s.drawToPile))
+	mux.Handle("POST /api/v1/deck/{id}/pile/{name}/draw", pkg.HttpHandler(s.drawFromPile))
+	mux.Handle("POST /api/v1/deck/{id}/pile/{name}/shuffle", pkg.HttpHandler(s.shufflePile))
 
 	server := &http.Server{
 		Addr:              s.config.Address,
@@ -116,6 +713,12 @@ func (s *Server) Run() {
 		ErrorLog:          slog.NewLogLogger(slog.Default().Handler(), slog.LevelError),
 	}
 
+	if memoryDeckProcessor, ok := s.deckProcessor.(*MemoryDeckProcessor); ok {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go memoryDeckProcessor.RunSweeper(ctx, sweepInterval)
+	}
+
 	if err := pkg.ServeWithShutdown(server); err != nil {
 		slog.Error("server shut down with error", pkg.Err(err))
 	}
@@ -142,26 +745,43 @@ func parseID(r *http.Request) (uuid.UUID, []pkg.InvalidParam) {
 	return id, invalidParams
 }
 
-func parseShuffled(r *http.Request) (bool, []pkg.InvalidParam) {
-	shuffledParamName := "shuffled"
+// parseBool parses optional boolean query parameter, missing parameter is false
+func parseBool(r *http.Request, paramName string) (bool, []pkg.InvalidParam) {
 	var invalidParams []pkg.InvalidParam
 
-	if !r.URL.Query().Has(shuffledParamName) {
+	if !r.URL.Query().Has(paramName) {
 		return false, invalidParams
 	}
 
-	shuffledStr := r.URL.Query().Get(shuffledParamName)
-	shuffled, err := strconv.ParseBool(shuffledStr)
+	valueStr := r.URL.Query().Get(paramName)
+	value, err := strconv.ParseBool(valueStr)
 	if err != nil {
 		invalidParams = append(invalidParams, pkg.InvalidParam{
-			Name:   shuffledParamName,
+			Name:   paramName,
 			Reason: err.Error(),
 		})
 	}
-	return shuffled, invalidParams
+	return value, invalidParams
+}
+
+// parseDeckType parses the deck type, the standard deck is used when the parameter is missing
+func parseDeckType(r *http.Request) (DeckTemplate, []pkg.InvalidParam) {
+	typeParamName := "type"
+	var invalidParams []pkg.InvalidParam
+
+	typeStr := r.URL.Query().Get(typeParamName)
+	template, ok := LookupDeckTemplate(typeStr)
+	if !ok {
+		invalidParams = append(invalidParams, pkg.InvalidParam{
+			Name:   typeParamName,
+			Reason: unknownDeckTypeReason(typeStr),
+		})
+	}
+	return template, invalidParams
 }
 
-func parseCards(r *http.Request) ([]string, []pkg.InvalidParam) {
+// parseCards parses card codes, every code has to belong to the template
+func parseCards(r *http.Request, template DeckTemplate) ([]string, []pkg.InvalidParam) {
 	cardsParamName := "cards"
 	var cards []string
 	var invalidParams []pkg.InvalidParam
@@ -171,7 +791,7 @@ func parseCards(r *http.Request) ([]string, []pkg.InvalidParam) {
 		return cards, invalidParams
 	}
 	cards = strings.Split(cardsStr, ",")
-	cardsByCode := generateAllCardsCombinationsByCode()
+	cardsByCode := generateAllCardsCombinationsByCode(template)
 	for _, card := range cards {
 		if _, ok := cardsByCode[card]; !ok {
 			invalidParams = append(invalidParams, pkg.InvalidParam{
@@ -211,3 +831,347 @@ func parseCount(r *http.Request) (int, []pkg.InvalidParam) {
 	}
 	return count, invalidParams
 }
+
+// parseHistoryPage parses the sequence number after which the page starts and the maximal number of its events
+func parseHistoryPage(r *http.Request) (int64, int, []pkg.InvalidParam) {
+	afterParamName := "after"
+	limitParamName := "limit"
+	var invalidParams []pkg.InvalidParam
+
+	var after int64
+	if afterStr := r.URL.Query().Get(afterParamName); afterStr != "" {
+		var err error
+		after, err = strconv.ParseInt(afterStr, 10, 64)
+		if err != nil {
+			invalidParams = append(invalidParams, pkg.InvalidParam{
+				Name:   afterParamName,
+				Reason: err.Error(),
+			})
+		} else if after < 0 {
+			invalidParams = append(invalidParams, pkg.InvalidParam{
+				Name:   afterParamName,
+				Reason: "after should be greater or equal to 0",
+			})
+		}
+	}
+
+	limit := DefaultHistoryLimit
+	if limitStr := r.URL.Query().Get(limitParamName); limitStr != "" {
+		var err error
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil {
+			invalidParams = append(invalidParams, pkg.InvalidParam{
+				Name:   limitParamName,
+				Reason: err.Error(),
+			})
+		} else if limit < 1 || limit > MaxHistoryLimit {
+			invalidParams = append(invalidParams, pkg.InvalidParam{
+				Name:   limitParamName,
+				Reason: fmt.Sprintf("limit should be between 1 and %d", MaxHistoryLimit),
+			})
+		}
+	}
+	return after, limit, invalidParams
+}
+
+func parseDecks(r *http.Request) (int, []pkg.InvalidParam) {
+	decksParamName := "decks"
+	var invalidParams []pkg.InvalidParam
+
+	decksStr := r.URL.Query().Get(decksParamName)
+	if decksStr == "" {
+		return 1, invalidParams
+	}
+
+	decks, err := strconv.Atoi(decksStr)
+	if err != nil {
+		invalidParams = append(invalidParams, pkg.InvalidParam{
+			Name:   decksParamName,
+			Reason: err.Error(),
+		})
+		return decks, invalidParams
+	}
+	if decks < 1 || decks > MaxDecks {
+		invalidParams = append(invalidParams, pkg.InvalidParam{
+			Name:   decksParamName,
+			Reason: fmt.Sprintf("decks should be between 1 and %d", MaxDecks),
+		})
+	}
+	return decks, invalidParams
+}
+
+// parseMaxPeek parses the optional peek limit of a sealed deck, zero means DefaultMaxPeek
+func parseMaxPeek(r *http.Request, sealed bool) (int, []pkg.InvalidParam) {
+	maxPeekParamName := "max_peek"
+	var invalidParams []pkg.InvalidParam
+
+	maxPeekStr := r.URL.Query().Get(maxPeekParamName)
+	if maxPeekStr == "" {
+		return 0, invalidParams
+	}
+
+	maxPeek, err := strconv.Atoi(maxPeekStr)
+	if err != nil {
+		invalidParams = append(invalidParams, pkg.InvalidParam{
+			Name:   maxPeekParamName,
+			Reason: err.Error(),
+		})
+		return 0, invalidParams
+	}
+	if maxPeek < 1 {
+		invalidParams = append(invalidParams, pkg.InvalidParam{
+			Name:   maxPeekParamName,
+			Reason: "max_peek should be greater or equal to 1",
+		})
+	}
+	if !sealed {
+		invalidParams = append(invalidParams, pkg.InvalidParam{
+			Name:   maxPeekParamName,
+			Reason: "max_peek applies only to sealed decks",
+		})
+	}
+	return maxPeek, invalidParams
+}
+
+// parseCardCodes parses required card codes, validation against the deck is left to the caller
+func parseCardCodes(r *http.Request) ([]string, []pkg.InvalidParam) {
+	cardsParamName := "cards"
+	var invalidParams []pkg.InvalidParam
+
+	cardsStr := r.URL.Query().Get(cardsParamName)
+	if cardsStr == "" {
+		invalidParams = append(invalidParams, pkg.InvalidParam{
+			Name:   cardsParamName,
+			Reason: "parameter missing",
+		})
+		return nil, invalidParams
+	}
+	return strings.Split(cardsStr, ","), invalidParams
+}
+
+// parsePosition parses position in the deck, top of the deck is used when the parameter is missing
+func parsePosition(r *http.Request) (string, []pkg.InvalidParam) {
+	positionParamName := "position"
+	var invalidParams []pkg.InvalidParam
+
+	position := r.URL.Query().Get(positionParamName)
+	switch position {
+	case "":
+		position = PositionTop
+	case PositionTop, PositionBottom, PositionRandom:
+	default:
+		invalidParams = append(invalidParams, pkg.InvalidParam{
+			Name:   positionParamName,
+			Reason: unknownPositionReason(position),
+		})
+	}
+	return position, invalidParams
+}
+
+func parseShuffleMethod(r *http.Request) (ShuffleMethod, []pkg.InvalidParam) {
+	modeParamName := "mode"
+	passesParamName := "passes"
+	var invalidParams []pkg.InvalidParam
+
+	mode := r.URL.Query().Get(modeParamName)
+	if !IsValidShuffleMode(mode) {
+		invalidParams = append(invalidParams, pkg.InvalidParam{
+			Name:   modeParamName,
+			Reason: unknownShuffleModeReason(mode),
+		})
+	}
+
+	passes := 0
+	passesStr := r.URL.Query().Get(passesParamName)
+	if passesStr != "" {
+		var err error
+		passes, err = strconv.Atoi(passesStr)
+		if err != nil || passes < 1 || passes > MaxShufflePasses {
+			invalidParams = append(invalidParams, pkg.InvalidParam{
+				Name:   passesParamName,
+				Reason: invalidShufflePassesReason(),
+			})
+		}
+	}
+	return ShuffleMethod{Mode: mode, Passes: passes}, invalidParams
+}
+
+// maxCardPredicates limits the number of predicates of a single stats request
+const maxCardPredicates = 16
+
+func parseCardPredicates(r *http.Request) ([]CardPredicate, []pkg.InvalidParam) {
+	matchParamName := "match"
+	var invalidParams []pkg.InvalidParam
+
+	expressions := r.URL.Query()[matchParamName]
+	if len(expressions) > maxCardPredicates {
+		invalidParams = append(invalidParams, pkg.InvalidParam{
+			Name:   matchParamName,
+			Reason: fmt.Sprintf("at most %d predicates can be matched at once", maxCardPredicates),
+		})
+		return nil, invalidParams
+	}
+
+	predicates := make([]CardPredicate, 0, len(expressions))
+	for _, expression := range expressions {
+		predicate, err := ParseCardPredicate(expression)
+		if err != nil {
+			invalidParams = append(invalidParams, pkg.InvalidParam{
+				Name:   matchParamName,
+				Reason: err.Error(),
+			})
+			continue
+		}
+		predicates = append(predicates, predicate)
+	}
+	return predicates, invalidParams
+}
+
+func parseHands(r *http.Request) ([]string, []pkg.InvalidParam) {
+	handsParamName := "hands"
+	var invalidParams []pkg.InvalidParam
+
+	handsStr := r.URL.Query().Get(handsParamName)
+	if handsStr == "" {
+		invalidParams = append(invalidParams, pkg.InvalidParam{
+			Name:   handsParamName,
+			Reason: "parameter missing",
+		})
+		return nil, invalidParams
+	}
+
+	hands := strings.Split(handsStr, ",")
+	if len(hands) > MaxHands {
+		invalidParams = append(invalidParams, pkg.InvalidParam{
+			Name:   handsParamName,
+			Reason: fmt.Sprintf("at most %d hands can be dealt at once", MaxHands),
+		})
+	}
+	seen := make(map[string]bool, len(hands))
+	for _, hand := range hands {
+		if !pileNamePattern.MatchString(hand) {
+			invalidParams = append(invalidParams, pkg.InvalidParam{
+				Name:   handsParamName,
+				Reason: fmt.Sprintf("hand %q should have 1 to 64 letters, digits, underscores or hyphens", hand),
+			})
+		} else if seen[hand] {
+			invalidParams = append(invalidParams, pkg.InvalidParam{
+				Name:   handsParamName,
+				Reason: fmt.Sprintf("hand %q is listed more than once", hand),
+			})
+		}
+		seen[hand] = true
+	}
+	return hands, invalidParams
+}
+
+func parseCutOptions(r *http.Request) (CutOptions, []pkg.InvalidParam) {
+	var invalidParams []pkg.InvalidParam
+	var options CutOptions
+
+	for _, param := range []struct {
+		name  string
+		value *int
+	}{
+		{name: "position", value: &options.Position},
+		{name: "min", value: &options.Min},
+		{name: "max", value: &options.Max},
+	} {
+		valueStr := r.URL.Query().Get(param.name)
+		if valueStr == "" {
+			continue
+		}
+		value, err := strconv.Atoi(valueStr)
+		if err != nil || value < 1 {
+			invalidParams = append(invalidParams, pkg.InvalidParam{
+				Name:   param.name,
+				Reason: fmt.Sprintf("%s should be a number greater or equal to 1", param.name),
+			})
+			continue
+		}
+		*param.value = value
+	}
+
+	if options.Position != 0 && (options.Min != 0 || options.Max != 0) {
+		invalidParams = append(invalidParams, pkg.InvalidParam{
+			Name:   "position",
+			Reason: "position can not be combined with min and max",
+		})
+	}
+	if options.Max != 0 && options.Min > options.Max {
+		invalidParams = append(invalidParams, pkg.InvalidParam{
+			Name:   "min",
+			Reason: "min should not be greater than max",
+		})
+	}
+	return options, invalidParams
+}
+
+func parsePileName(r *http.Request) (string, []pkg.InvalidParam) {
+	nameParamName := "name"
+	var invalidParams []pkg.InvalidParam
+
+	name := r.PathValue(nameParamName)
+	if !pileNamePattern.MatchString(name) {
+		invalidParams = append(invalidParams, pkg.InvalidParam{
+			Name:   nameParamName,
+			Reason: "pile name should have 1 to 64 letters, digits, underscores or hyphens",
+		})
+	}
+	return name, invalidParams
+}
+
+// parseCardSelector parses either the list of drawn cards, or the count of cards and their position
+func parseCardSelector(r *http.Request) (CardSelector, []pkg.InvalidParam) {
+	if r.URL.Query().Get("cards") != "" {
+		codes, invalidParams := parseCardCodes(r)
+		return CardSelector{Codes: codes}, invalidParams
+	}
+
+	var invalidParams []pkg.InvalidParam
+
+	count, countErrors := parseCount(r)
+	invalidParams = append(invalidParams, countErrors...)
+
+	position, positionErrors := parsePosition(r)
+	invalidParams = append(invalidParams, positionErrors...)
+
+	return CardSelector{Count: count, Position: position}, invalidParams
+}
+
+// parseSeed parses optional seed of the shuffle, nil is returned when the parameter is missing
+func parseSeed(r *http.Request) (*uint64, []pkg.InvalidParam) {
+	seedParamName := "seed"
+	var invalidParams []pkg.InvalidParam
+
+	if !r.URL.Query().Has(seedParamName) {
+		return nil, invalidParams
+	}
+
+	seed, err := strconv.ParseUint(r.URL.Query().Get(seedParamName), 10, 64)
+	if err != nil {
+		invalidParams = append(invalidParams, pkg.InvalidParam{
+			Name:   seedParamName,
+			Reason: err.Error(),
+		})
+		return nil, invalidParams
+	}
+	return &seed, invalidParams
+}
+
+// maxClientSeedLength limits the client seed of provably fair decks
+const maxClientSeedLength = 256
+
+func parseClientSeed(r *http.Request) (string, []pkg.InvalidParam) {
+	clientSeedParamName := "client_seed"
+	var invalidParams []pkg.InvalidParam
+
+	clientSeed := r.URL.Query().Get(clientSeedParamName)
+	if len(clientSeed) > maxClientSeedLength {
+		invalidParams = append(invalidParams, pkg.InvalidParam{
+			Name:   clientSeedParamName,
+			Reason: fmt.Sprintf("client seed should have at most %d bytes", maxClientSeedLength),
+		})
+	}
+	return clientSeed, invalidParams
+}
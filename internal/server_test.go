@@ -4,14 +4,15 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
 	"net/http"
 	"net/http/httptest"
 	"slices"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"testing"
 
-	"github.com/google/uuid"
 	"github.com/prathoss/cards/pkg"
 )
 
@@ -64,10 +65,66 @@ func TestParseCount(t *testing.T) {
 	}
 }
 
+func TestParseDecks(t *testing.T) {
+	tests := []struct {
+		name          string
+		reqURL        string
+		expectedDecks int
+		expectedError bool
+	}{
+		{
+			name:          "MissingParameter",
+			reqURL:        "/",
+			expectedDecks: 1,
+			expectedError: false,
+		},
+		{
+			name:          "ValidParameter",
+			reqURL:        "/?decks=6",
+			expectedDecks: 6,
+			expectedError: false,
+		},
+		{
+			name:          "NonIntParameter",
+			reqURL:        "/?decks=six",
+			expectedDecks: 0,
+			expectedError: true,
+		},
+		{
+			name:          "ZeroParameter",
+			reqURL:        "/?decks=0",
+			expectedDecks: 0,
+			expectedError: true,
+		},
+		{
+			name:          "TooManyDecks",
+			reqURL:        "/?decks=9",
+			expectedDecks: 9,
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.reqURL, nil)
+			gotDecks, gotInvalidParams := parseDecks(req)
+
+			if gotDecks != tt.expectedDecks {
+				t.Errorf("parseDecks() gotDecks = %v, expectedDecks = %v", gotDecks, tt.expectedDecks)
+			}
+
+			if (len(gotInvalidParams) > 0) != tt.expectedError {
+				t.Errorf("parseDecks() gotInvalidParams = %v, expectedError = %v", gotInvalidParams, tt.expectedError)
+			}
+		})
+	}
+}
+
 func TestParseCards(t *testing.T) {
 	tests := []struct {
 		desc           string
 		reqURL         string
+		deckType       string
 		expectedCards  []string
 		expectedErrors []pkg.InvalidParam
 	}{
@@ -92,6 +149,24 @@ func TestParseCards(t *testing.T) {
 				},
 			},
 		},
+		{
+			desc:           "Jokers",
+			reqURL:         "/?cards=XR,XB,AS",
+			expectedCards:  []string{"XR", "XB", "AS"},
+			expectedErrors: nil,
+		},
+		{
+			desc:          "Card not in deck type",
+			reqURL:        "/?cards=KH,2H",
+			deckType:      DeckTypePiquet,
+			expectedCards: []string{"KH", "2H"},
+			expectedErrors: []pkg.InvalidParam{
+				{
+					Name:   "cards",
+					Reason: "unrecognised card: 2H",
+				},
+			},
+		},
 		{
 			desc:           "Empty card parameter",
 			reqURL:         "/?cards=",
@@ -109,7 +184,8 @@ func TestParseCards(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.desc, func(t *testing.T) {
 			req := httptest.NewRequest(http.MethodGet, tt.reqURL, nil)
-			cards, parseErrors := parseCards(req)
+			template, _ := LookupDeckTemplate(tt.deckType)
+			cards, parseErrors := parseCards(req, template)
 
 			if !slices.Equal(cards, tt.expectedCards) {
 				t.Errorf("Expected cards %v, but got %v", tt.expectedCards, cards)
@@ -121,90 +197,1051 @@ func TestParseCards(t *testing.T) {
 	}
 }
 
-var _ DeckProcessor = (*DeckProcessorMock)(nil)
+func TestParsePosition(t *testing.T) {
+	tests := []struct {
+		name             string
+		reqURL           string
+		expectedPosition string
+		expectedError    bool
+	}{
+		{
+			name:             "MissingParameter",
+			reqURL:           "/",
+			expectedPosition: PositionTop,
+		},
+		{
+			name:             "Bottom",
+			reqURL:           "/?position=bottom",
+			expectedPosition: PositionBottom,
+		},
+		{
+			name:             "Random",
+			reqURL:           "/?position=random",
+			expectedPosition: PositionRandom,
+		},
+		{
+			name:             "UnknownPosition",
+			reqURL:           "/?position=middle",
+			expectedPosition: "middle",
+			expectedError:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.reqURL, nil)
+			gotPosition, gotInvalidParams := parsePosition(req)
 
-type DeckProcessorMock struct {
-	storage map[uuid.UUID]*Deck
-}
+			if gotPosition != tt.expectedPosition {
+				t.Errorf("parsePosition() gotPosition = %v, expectedPosition = %v", gotPosition, tt.expectedPosition)
+			}
 
-func (d *DeckProcessorMock) Create(_ context.Context, cardsCodes []string, shuffled bool) (Deck, error) {
-	deck, err := NewDeck(cardsCodes, shuffled)
-	if err != nil {
-		return Deck{}, err
+			if (len(gotInvalidParams) > 0) != tt.expectedError {
+				t.Errorf("parsePosition() gotInvalidParams = %v, expectedError = %v", gotInvalidParams, tt.expectedError)
+			}
+		})
 	}
-	d.storage[deck.ID] = &deck
-	return deck, nil
 }
 
-func (d *DeckProcessorMock) Get(_ context.Context, deckID uuid.UUID) (Deck, error) {
-	deck, ok := d.storage[deckID]
-	if !ok {
-		return Deck{}, pkg.NewNotFoundError("deck not found")
+func TestParsePileName(t *testing.T) {
+	tests := []struct {
+		name          string
+		pile          string
+		expectedError bool
+	}{
+		{name: "Simple", pile: "discard"},
+		{name: "WithDigitsAndSeparators", pile: "player_1-hand"},
+		{name: "Empty", pile: "", expectedError: true},
+		{name: "Dot", pile: "player.hand", expectedError: true},
+		{name: "Dollar", pile: "$hand", expectedError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.SetPathValue("name", tt.pile)
+			gotName, gotInvalidParams := parsePileName(req)
+
+			if gotName != tt.pile {
+				t.Errorf("parsePileName() gotName = %v, expected = %v", gotName, tt.pile)
+			}
+
+			if (len(gotInvalidParams) > 0) != tt.expectedError {
+				t.Errorf("parsePileName() gotInvalidParams = %v, expectedError = %v", gotInvalidParams, tt.expectedError)
+			}
+		})
 	}
-	return *deck, nil
 }
 
-func (d *DeckProcessorMock) DrawCards(ctx context.Context, deckID uuid.UUID, count int) ([]Card, error) {
-	deck, err := d.Get(ctx, deckID)
-	if err != nil {
-		return nil, err
+func TestParseCardSelector(t *testing.T) {
+	tests := []struct {
+		name             string
+		reqURL           string
+		expectedSelector CardSelector
+		expectedError    bool
+	}{
+		{
+			name:             "CountFromTop",
+			reqURL:           "/?count=2",
+			expectedSelector: CardSelector{Count: 2, Position: PositionTop},
+		},
+		{
+			name:             "CountFromBottom",
+			reqURL:           "/?count=3&position=bottom",
+			expectedSelector: CardSelector{Count: 3, Position: PositionBottom},
+		},
+		{
+			name:             "Cards",
+			reqURL:           "/?cards=AS,KH",
+			expectedSelector: CardSelector{Codes: []string{"AS", "KH"}},
+		},
+		{
+			name:             "MissingCount",
+			reqURL:           "/?position=bottom",
+			expectedSelector: CardSelector{Position: PositionBottom},
+			expectedError:    true,
+		},
 	}
 
-	cards, err := deck.DrawCards(count)
-	if err != nil {
-		return nil, err
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.reqURL, nil)
+			gotSelector, gotInvalidParams := parseCardSelector(req)
+
+			if gotSelector.Count != tt.expectedSelector.Count ||
+				gotSelector.Position != tt.expectedSelector.Position ||
+				!slices.Equal(gotSelector.Codes, tt.expectedSelector.Codes) {
+				t.Errorf("parseCardSelector() gotSelector = %v, expectedSelector = %v", gotSelector, tt.expectedSelector)
+			}
+
+			if (len(gotInvalidParams) > 0) != tt.expectedError {
+				t.Errorf("parseCardSelector() gotInvalidParams = %v, expectedError = %v", gotInvalidParams, tt.expectedError)
+			}
+		})
 	}
-	d.storage[deckID] = &deck
-	return cards, nil
 }
 
-func TestDeck_Draw_Concurrency(t *testing.T) {
-	s := &Server{
-		config: Config{},
-		deckProcessor: &DeckProcessorMock{
-			storage: map[uuid.UUID]*Deck{},
+func TestParseSeed(t *testing.T) {
+	tests := []struct {
+		name          string
+		reqURL        string
+		expectedSeed  *uint64
+		expectedError bool
+	}{
+		{
+			name:         "MissingParameter",
+			reqURL:       "/",
+			expectedSeed: nil,
+		},
+		{
+			name:         "ValidParameter",
+			reqURL:       "/?seed=18446744073709551615",
+			expectedSeed: func() *uint64 { seed := uint64(18446744073709551615); return &seed }(),
+		},
+		{
+			name:          "NegativeParameter",
+			reqURL:        "/?seed=-1",
+			expectedError: true,
+		},
+		{
+			name:          "NonIntParameter",
+			reqURL:        "/?seed=abc",
+			expectedError: true,
 		},
 	}
 
-	deck, err := s.deckProcessor.Create(context.Background(), nil, false)
-	if err != nil {
-		t.Fatal(err)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.reqURL, nil)
+			gotSeed, gotInvalidParams := parseSeed(req)
+
+			if (gotSeed == nil) != (tt.expectedSeed == nil) || (gotSeed != nil && *gotSeed != *tt.expectedSeed) {
+				t.Errorf("parseSeed() gotSeed = %v, expectedSeed = %v", gotSeed, tt.expectedSeed)
+			}
+
+			if (len(gotInvalidParams) > 0) != tt.expectedError {
+				t.Errorf("parseSeed() gotInvalidParams = %v, expectedError = %v", gotInvalidParams, tt.expectedError)
+			}
+		})
 	}
+}
 
-	concurrencyDegree := 200
-	wg := &sync.WaitGroup{}
+func TestParseShuffleMethod(t *testing.T) {
+	tests := []struct {
+		name           string
+		reqURL         string
+		expectedMethod ShuffleMethod
+		expectedError  bool
+	}{
+		{
+			name:           "MissingParameters",
+			reqURL:         "/",
+			expectedMethod: ShuffleMethod{},
+		},
+		{
+			name:           "ModeAndPasses",
+			reqURL:         "/?mode=riffle&passes=3",
+			expectedMethod: ShuffleMethod{Mode: ShuffleModeRiffle, Passes: 3},
+		},
+		{
+			name:          "UnknownMode",
+			reqURL:        "/?mode=shake",
+			expectedError: true,
+		},
+		{
+			name:          "ZeroPasses",
+			reqURL:        "/?mode=riffle&passes=0",
+			expectedError: true,
+		},
+		{
+			name:          "TooManyPasses",
+			reqURL:        fmt.Sprintf("/?mode=riffle&passes=%d", MaxShufflePasses+1),
+			expectedError: true,
+		},
+		{
+			name:          "NonIntPasses",
+			reqURL:        "/?passes=abc",
+			expectedError: true,
+		},
+	}
 
-	cardsReceived := atomic.Int32{}
-	expectedError := newNotEnoughCardsError()
-	nonExpectedErrorsCount := atomic.Int32{}
-	for range concurrencyDegree {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			recorder := httptest.NewRecorder()
-			req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/deck/%s/draw?count=1", deck.ID.String()), nil)
-			req.SetPathValue("id", deck.ID.String())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.reqURL, nil)
+			gotMethod, gotInvalidParams := parseShuffleMethod(req)
 
-			response, err := s.drawCards(recorder, req)
+			if !tt.expectedError && gotMethod != tt.expectedMethod {
+				t.Errorf("parseShuffleMethod() gotMethod = %v, expectedMethod = %v", gotMethod, tt.expectedMethod)
+			}
 
-			if err != nil {
-				var badRequestError *pkg.BadRequestError
-				if !errors.As(err, &badRequestError) && badRequestError.Error() != expectedError.Error() {
-					nonExpectedErrorsCount.Add(1)
+			if (len(gotInvalidParams) > 0) != tt.expectedError {
+				t.Errorf("parseShuffleMethod() gotInvalidParams = %v, expectedError = %v", gotInvalidParams, tt.expectedError)
+			}
+		})
+	}
+}
+
+func TestParseCardPredicates(t *testing.T) {
+	tests := []struct {
+		name                string
+		reqURL              string
+		expectedExpressions []string
+		expectedError       bool
+	}{
+		{
+			name:                "MissingParameter",
+			reqURL:              "/",
+			expectedExpressions: []string{},
+		},
+		{
+			name:                "ValidParameters",
+			reqURL:              "/?match=color:red&match=suit:spades,rank:high",
+			expectedExpressions: []string{"color:red", "suit:spades,rank:high"},
+		},
+		{
+			name:          "InvalidPredicate",
+			reqURL:        "/?match=color:red&match=shape:round",
+			expectedError: true,
+		},
+		{
+			name:          "TooManyPredicates",
+			reqURL:        "/?" + strings.Repeat("match=color:red&", maxCardPredicates+1),
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.reqURL, nil)
+			gotPredicates, gotInvalidParams := parseCardPredicates(req)
+
+			if !tt.expectedError {
+				gotExpressions := make([]string, 0, len(gotPredicates))
+				for _, predicate := range gotPredicates {
+					gotExpressions = append(gotExpressions, predicate.Expression)
+				}
+				if !slices.Equal(gotExpressions, tt.expectedExpressions) {
+					t.Errorf("parseCardPredicates() gotExpressions = %v, expectedExpressions = %v", gotExpressions, tt.expectedExpressions)
 				}
-				return
 			}
-			cardsResponse := response.(CardsResponse)
-			cardsReceived.Add(int32(len(cardsResponse.Cards)))
-		}()
+
+			if (len(gotInvalidParams) > 0) != tt.expectedError {
+				t.Errorf("parseCardPredicates() gotInvalidParams = %v, expectedError = %v", gotInvalidParams, tt.expectedError)
+			}
+		})
 	}
-	wg.Wait()
+}
 
-	if nonExpectedErrorsCount.Load() > 0 {
-		t.Fatalf("handler returns unexpected errors")
+func TestParseHistoryPage(t *testing.T) {
+	tests := []struct {
+		name          string
+		reqURL        string
+		expectedAfter int64
+		expectedLimit int
+		expectedError bool
+	}{
+		{
+			name:          "Defaults",
+			reqURL:        "/",
+			expectedAfter: 0,
+			expectedLimit: DefaultHistoryLimit,
+		},
+		{
+			name:          "ValidParameters",
+			reqURL:        "/?after=10&limit=5",
+			expectedAfter: 10,
+			expectedLimit: 5,
+		},
+		{
+			name:          "NegativeAfter",
+			reqURL:        "/?after=-1",
+			expectedError: true,
+		},
+		{
+			name:          "InvalidAfter",
+			reqURL:        "/?after=first",
+			expectedError: true,
+		},
+		{
+			name:          "ZeroLimit",
+			reqURL:        "/?limit=0",
+			expectedError: true,
+		},
+		{
+			name:          "LimitTooHigh",
+			reqURL:        fmt.Sprintf("/?limit=%d", MaxHistoryLimit+1),
+			expectedError: true,
+		},
 	}
 
-	if cardsReceived.Load() > int32(len(deck.Cards)) {
-		t.Fatalf("drew more cards than possible")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.reqURL, nil)
+			gotAfter, gotLimit, gotInvalidParams := parseHistoryPage(req)
+
+			if !tt.expectedError && (gotAfter != tt.expectedAfter || gotLimit != tt.expectedLimit) {
+				t.Errorf("parseHistoryPage() gotAfter = %d, gotLimit = %d, expected %d and %d", gotAfter, gotLimit, tt.expectedAfter, tt.expectedLimit)
+			}
+
+			if (len(gotInvalidParams) > 0) != tt.expectedError {
+				t.Errorf("parseHistoryPage() gotInvalidParams = %v, expectedError = %v", gotInvalidParams, tt.expectedError)
+			}
+		})
+	}
+}
+
+func TestParseHands(t *testing.T) {
+	tests := []struct {
+		name          string
+		reqURL        string
+		expectedHands []string
+		expectedError bool
+	}{
+		{
+			name:          "MissingParameter",
+			reqURL:        "/",
+			expectedError: true,
+		},
+		{
+			name:          "ValidParameter",
+			reqURL:        "/?hands=alice,bob",
+			expectedHands: []string{"alice", "bob"},
+		},
+		{
+			name:          "InvalidName",
+			reqURL:        "/?hands=alice,b.o.b",
+			expectedError: true,
+		},
+		{
+			name:          "EmptyName",
+			reqURL:        "/?hands=alice,",
+			expectedError: true,
+		},
+		{
+			name:          "DuplicateName",
+			reqURL:        "/?hands=alice,bob,alice",
+			expectedError: true,
+		},
+		{
+			name:          "TooManyHands",
+			reqURL:        "/?hands=" + strings.Repeat("a,", MaxHands) + "b",
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.reqURL, nil)
+			gotHands, gotInvalidParams := parseHands(req)
+
+			if !tt.expectedError && !slices.Equal(gotHands, tt.expectedHands) {
+				t.Errorf("parseHands() gotHands = %v, expectedHands = %v", gotHands, tt.expectedHands)
+			}
+
+			if (len(gotInvalidParams) > 0) != tt.expectedError {
+				t.Errorf("parseHands() gotInvalidParams = %v, expectedError = %v", gotInvalidParams, tt.expectedError)
+			}
+		})
+	}
+}
+
+func TestParseCutOptions(t *testing.T) {
+	tests := []struct {
+		name            string
+		reqURL          string
+		expectedOptions CutOptions
+		expectedError   bool
+	}{
+		{
+			name:            "MissingParameters",
+			reqURL:          "/",
+			expectedOptions: CutOptions{},
+		},
+		{
+			name:            "Position",
+			reqURL:          "/?position=12",
+			expectedOptions: CutOptions{Position: 12},
+		},
+		{
+			name:            "Bounds",
+			reqURL:          "/?min=4&max=48",
+			expectedOptions: CutOptions{Min: 4, Max: 48},
+		},
+		{
+			name:          "PositionWithBounds",
+			reqURL:        "/?position=12&min=4",
+			expectedError: true,
+		},
+		{
+			name:          "MinAboveMax",
+			reqURL:        "/?min=10&max=4",
+			expectedError: true,
+		},
+		{
+			name:          "ZeroPosition",
+			reqURL:        "/?position=0",
+			expectedError: true,
+		},
+		{
+			name:          "NonIntMax",
+			reqURL:        "/?max=abc",
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.reqURL, nil)
+			gotOptions, gotInvalidParams := parseCutOptions(req)
+
+			if !tt.expectedError && gotOptions != tt.expectedOptions {
+				t.Errorf("parseCutOptions() gotOptions = %v, expectedOptions = %v", gotOptions, tt.expectedOptions)
+			}
+
+			if (len(gotInvalidParams) > 0) != tt.expectedError {
+				t.Errorf("parseCutOptions() gotInvalidParams = %v, expectedError = %v", gotInvalidParams, tt.expectedError)
+			}
+		})
+	}
+}
+
+func TestParseClientSeed(t *testing.T) {
+	tests := []struct {
+		name               string
+		reqURL             string
+		expectedClientSeed string
+		expectedError      bool
+	}{
+		{
+			name:               "MissingParameter",
+			reqURL:             "/",
+			expectedClientSeed: "",
+		},
+		{
+			name:               "ValidParameter",
+			reqURL:             "/?client_seed=lucky",
+			expectedClientSeed: "lucky",
+		},
+		{
+			name:          "TooLongParameter",
+			reqURL:        "/?client_seed=" + strings.Repeat("a", maxClientSeedLength+1),
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.reqURL, nil)
+			gotClientSeed, gotInvalidParams := parseClientSeed(req)
+
+			if !tt.expectedError && gotClientSeed != tt.expectedClientSeed {
+				t.Errorf("parseClientSeed() gotClientSeed = %v, expectedClientSeed = %v", gotClientSeed, tt.expectedClientSeed)
+			}
+
+			if (len(gotInvalidParams) > 0) != tt.expectedError {
+				t.Errorf("parseClientSeed() gotInvalidParams = %v, expectedError = %v", gotInvalidParams, tt.expectedError)
+			}
+		})
+	}
+}
+
+func TestServer_OpenDeck_SeedOnlyForAdmin(t *testing.T) {
+	s := &Server{
+		config:        Config{AdminToken: "secret"},
+		deckProcessor: NewMemoryDeckProcessor(CryptoRandomSource{}),
+	}
+	seed := uint64(42)
+	deck, err := s.deckProcessor.Create(context.Background(), DeckOptions{Shuffled: true, Seed: &seed})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		authorization string
+		wantSeed      bool
+	}{
+		{name: "Anonymous", authorization: "", wantSeed: false},
+		{name: "WrongToken", authorization: "Bearer wrong", wantSeed: false},
+		{name: "Admin", authorization: "Bearer secret", wantSeed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/deck/%s/open", deck.ID), nil)
+			req.SetPathValue("id", deck.ID.String())
+			req.Header.Set("Authorization", tt.authorization)
+
+			response, err := s.openDeck(httptest.NewRecorder(), req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			gotSeed := response.(OpenDeckResponse).Seed
+			if (gotSeed != nil) != tt.wantSeed {
+				t.Errorf("openDeck() seed = %v, wantSeed %v", gotSeed, tt.wantSeed)
+			}
+		})
+	}
+}
+
+func TestServer_OpenDeck_Sealed(t *testing.T) {
+	s := &Server{
+		deckProcessor: NewMemoryDeckProcessor(CryptoRandomSource{}),
+	}
+	deck, err := s.deckProcessor.Create(context.Background(), DeckOptions{Sealed: true, MaxPeek: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/deck/%s/open", deck.ID), nil)
+	req.SetPathValue("id", deck.ID.String())
+	_, err = s.openDeck(httptest.NewRecorder(), req)
+	var forbiddenError *pkg.ForbiddenError
+	if !errors.As(err, &forbiddenError) {
+		t.Errorf("openDeck() error = %v, want ForbiddenError", err)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/deck/%s/peek?count=2", deck.ID), nil)
+	req.SetPathValue("id", deck.ID.String())
+	response, err := s.peekCards(httptest.NewRecorder(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := response.(CardsResponse).Cards; len(got) != 2 || got[0].Code != deck.Cards[0].Code() {
+		t.Errorf("peekCards() cards = %v, want top 2 cards of the deck", got)
+	}
+}
+
+func TestServer_PeekCards_SealedLimit(t *testing.T) {
+	s := &Server{
+		deckProcessor: NewMemoryDeckProcessor(CryptoRandomSource{}),
+	}
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/deck?sealed=true&shuffled=true", nil)
+	created, err := s.createDeck(httptest.NewRecorder(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := created.(CreateDeckResponse).ID
+
+	tests := []struct {
+		name    string
+		query   string
+		wantErr bool
+	}{
+		{name: "Top", query: "count=1&position=top", wantErr: false},
+		{name: "Bottom", query: "count=1&position=bottom", wantErr: false},
+		{name: "OverLimit", query: "count=2&position=top", wantErr: true},
+		{name: "WholeDeck", query: "count=52&position=top", wantErr: true},
+	}
+
+	revealed := make(map[string]struct{})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/v1/deck/%s/peek?%s", id, tt.query), nil)
+			req.SetPathValue("id", id.String())
+			response, err := s.peekCards(httptest.NewRecorder(), req)
+			if tt.wantErr {
+				var badRequestError *pkg.BadRequestError
+				if !errors.As(err, &badRequestError) {
+					t.Errorf("peekCards() error = %v, want BadRequestError", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, card := range response.(CardsResponse).Cards {
+				revealed[card.Code] = struct{}{}
+			}
+		})
+	}
+	if len(revealed) != 2 {
+		t.Errorf("peekCards() revealed %d cards of the sealed deck, want only the top and the bottom one", len(revealed))
+	}
+}
+
+func TestServer_OpenDeck_Hidden(t *testing.T) {
+	s := &Server{
+		config:        Config{AdminToken: "secret"},
+		deckProcessor: NewMemoryDeckProcessor(CryptoRandomSource{}),
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/deck?hidden=true&cards=AS,KH,2S", nil)
+	created, err := s.createDeck(httptest.NewRecorder(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deckResponse := created.(CreateDeckResponse)
+	if deckResponse.OwnerToken == "" || !deckResponse.Hidden {
+		t.Fatalf("createDeck() owner token = %q, hidden = %v, want token of a hidden deck", deckResponse.OwnerToken, deckResponse.Hidden)
+	}
+
+	tests := []struct {
+		name          string
+		authorization string
+		wantCards     bool
+	}{
+		{name: "Anonymous", authorization: "", wantCards: false},
+		{name: "WrongToken", authorization: "Bearer wrong", wantCards: false},
+		{name: "TokenWithoutBearer", authorization: deckResponse.OwnerToken, wantCards: false},
+		{name: "Admin", authorization: "Bearer secret", wantCards: false},
+		{name: "Owner", authorization: "Bearer " + deckResponse.OwnerToken, wantCards: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/deck/%s/open", deckResponse.ID), nil)
+			req.SetPathValue("id", deckResponse.ID.String())
+			req.Header.Set("Authorization", tt.authorization)
+
+			response, err := s.openDeck(httptest.NewRecorder(), req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			switch response := response.(type) {
+			case OpenDeckResponse:
+				if !tt.wantCards {
+					t.Errorf("openDeck() revealed cards %v", response.Cards)
+				}
+			case HiddenDeckResponse:
+				if tt.wantCards {
+					t.Errorf("openDeck() did not reveal cards to the owner")
+				}
+				if want := map[string]int{CardSuitSpades: 2, CardSuitHearths: 1}; !maps.Equal(response.Suits, want) {
+					t.Errorf("openDeck() suits = %v, want %v", response.Suits, want)
+				}
+			default:
+				t.Fatalf("openDeck() unexpected response %T", response)
+			}
+		})
+	}
+}
+
+func TestServer_HiddenDeck_RevealsOnlyToOwner(t *testing.T) {
+	s := &Server{
+		deckProcessor: NewMemoryDeckProcessor(CryptoRandomSource{}),
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/deck?hidden=true&cards=AS,KH,2S,3D", nil)
+	created, err := s.createDeck(httptest.NewRecorder(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deckResponse := created.(CreateDeckResponse)
+	if _, err := s.deckProcessor.DrawToPile(context.Background(), deckResponse.ID, "discard", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	handlers := []struct {
+		name    string
+		method  string
+		path    string
+		handler func(w http.ResponseWriter, r *http.Request) (any, error)
+	}{
+		{name: "Peek", method: http.MethodGet, path: "peek?count=1", handler: s.peekCards},
+		{name: "Pile", method: http.MethodGet, path: "pile/discard", handler: s.listPile},
+		{name: "CutReveal", method: http.MethodPost, path: "cut?position=1&reveal=true", handler: s.cutDeck},
+	}
+	tests := []struct {
+		name          string
+		authorization string
+		wantForbidden bool
+	}{
+		{name: "Anonymous", authorization: "", wantForbidden: true},
+		{name: "WrongToken", authorization: "Bearer wrong", wantForbidden: true},
+		{name: "Owner", authorization: "Bearer " + deckResponse.OwnerToken, wantForbidden: false},
+	}
+
+	for _, h := range handlers {
+		for _, tt := range tests {
+			t.Run(h.name+"/"+tt.name, func(t *testing.T) {
+				before, err := s.deckProcessor.Get(context.Background(), deckResponse.ID)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				req := httptest.NewRequest(h.method, fmt.Sprintf("/api/v1/deck/%s/%s", deckResponse.ID, h.path), nil)
+				req.SetPathValue("id", deckResponse.ID.String())
+				req.SetPathValue("name", "discard")
+				req.Header.Set("Authorization", tt.authorization)
+
+				_, err = h.handler(httptest.NewRecorder(), req)
+				var forbiddenError *pkg.ForbiddenError
+				if errors.As(err, &forbiddenError) != tt.wantForbidden {
+					t.Fatalf("%s error = %v, wantForbidden %v", h.name, err, tt.wantForbidden)
+				}
+				if !tt.wantForbidden {
+					return
+				}
+				after, err := s.deckProcessor.Get(context.Background(), deckResponse.ID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if after.Version != before.Version {
+					t.Errorf("%s modified the deck although the reveal is forbidden", h.name)
+				}
+			})
+		}
+	}
+}
+
+func TestServer_HiddenDeck_PileModificationsRevealOnlyToOwner(t *testing.T) {
+	s := &Server{
+		deckProcessor: NewMemoryDeckProcessor(CryptoRandomSource{}),
+	}
+	token, err := NewOwnerToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	deck, err := s.deckProcessor.Create(context.Background(), DeckOptions{Shuffled: true, Hidden: true, OwnerToken: token})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handlers := []struct {
+		name    string
+		path    string
+		handler func(w http.ResponseWriter, r *http.Request) (any, error)
+	}{
+		{name: "DrawToPile", path: "pile/discard/add?count=2", handler: s.drawToPile},
+		{name: "ShufflePile", path: "pile/discard/shuffle", handler: s.shufflePile},
+	}
+	tests := []struct {
+		name          string
+		authorization string
+		wantCards     bool
+	}{
+		{name: "Anonymous", authorization: "", wantCards: false},
+		{name: "Owner", authorization: "Bearer " + token, wantCards: true},
+	}
+
+	for _, h := range handlers {
+		for _, tt := range tests {
+			t.Run(h.name+"/"+tt.name, func(t *testing.T) {
+				req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/deck/%s/%s", deck.ID, h.path), nil)
+				req.SetPathValue("id", deck.ID.String())
+				req.SetPathValue("name", "discard")
+				req.Header.Set("Authorization", tt.authorization)
+
+				response, err := h.handler(httptest.NewRecorder(), req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				pile := response.(PileResponse)
+				if pile.Remaining == 0 || (pile.Cards != nil) != tt.wantCards {
+					t.Errorf("%s pile = %+v, wantCards %v", h.name, pile, tt.wantCards)
+				}
+			})
+		}
+	}
+}
+
+func TestDeck_Draw_Concurrency(t *testing.T) {
+	s := &Server{
+		config:        Config{},
+		deckProcessor: NewMemoryDeckProcessor(CryptoRandomSource{}),
+	}
+
+	deck, err := s.deckProcessor.Create(context.Background(), DeckOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	concurrencyDegree := 200
+	wg := &sync.WaitGroup{}
+
+	cardsReceived := atomic.Int32{}
+	expectedError := newNotEnoughCardsError()
+	nonExpectedErrorsCount := atomic.Int32{}
+	for range concurrencyDegree {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			recorder := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/deck/%s/draw?count=1", deck.ID.String()), nil)
+			req.SetPathValue("id", deck.ID.String())
+
+			response, err := s.drawCards(recorder, req)
+
+			if err != nil {
+				var badRequestError *pkg.BadRequestError
+				if !errors.As(err, &badRequestError) && badRequestError.Error() != expectedError.Error() {
+					nonExpectedErrorsCount.Add(1)
+				}
+				return
+			}
+			cardsResponse := response.(CardsResponse)
+			cardsReceived.Add(int32(len(cardsResponse.Cards)))
+		}()
+	}
+	wg.Wait()
+
+	if nonExpectedErrorsCount.Load() > 0 {
+		t.Fatalf("handler returns unexpected errors")
+	}
+
+	if cardsReceived.Load() > int32(len(deck.Cards)) {
+		t.Fatalf("drew more cards than possible")
+	}
+}
+
+func TestServer_CreateDeckClone_Restricted(t *testing.T) {
+	s := &Server{
+		deckProcessor: NewMemoryDeckProcessor(CryptoRandomSource{}),
+	}
+	token, err := NewOwnerToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hidden, err := s.deckProcessor.Create(context.Background(), DeckOptions{Shuffled: true, Hidden: true, OwnerToken: token})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := s.deckProcessor.Create(context.Background(), DeckOptions{Shuffled: true, Sealed: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		deck          Deck
+		authorization string
+		wantForbidden bool
+	}{
+		{name: "HiddenAnonymous", deck: hidden, authorization: "", wantForbidden: true},
+		{name: "HiddenWrongToken", deck: hidden, authorization: "Bearer wrong", wantForbidden: true},
+		{name: "HiddenOwner", deck: hidden, authorization: "Bearer " + token, wantForbidden: false},
+		{name: "SealedAnonymous", deck: sealed, authorization: "", wantForbidden: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/deck/%s/clone", tt.deck.ID), nil)
+			req.SetPathValue("id", tt.deck.ID.String())
+			req.Header.Set("Authorization", tt.authorization)
+
+			_, err := s.createDeckClone(httptest.NewRecorder(), req)
+			var forbiddenError *pkg.ForbiddenError
+			if errors.As(err, &forbiddenError) != tt.wantForbidden {
+				t.Errorf("createDeckClone() error = %v, wantForbidden %v", err, tt.wantForbidden)
+			}
+		})
+	}
+}
+
+func TestServer_FairHiddenDeck_RevealsOnlyToOwner(t *testing.T) {
+	s := &Server{
+		deckProcessor: NewMemoryDeckProcessor(CryptoRandomSource{}),
+	}
+	token, err := NewOwnerToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	deck, err := s.deckProcessor.Create(ctx, DeckOptions{Fair: true, Hidden: true, OwnerToken: token})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.deckProcessor.SeedFairness(ctx, deck.ID, "lucky"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.deckProcessor.Close(ctx, deck.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		authorization string
+		wantRevealed  bool
+	}{
+		{name: "Anonymous", authorization: "", wantRevealed: false},
+		{name: "Owner", authorization: "Bearer " + token, wantRevealed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/deck/%s/open", deck.ID), nil)
+			req.SetPathValue("id", deck.ID.String())
+			req.Header.Set("Authorization", tt.authorization)
+			response, err := s.openDeck(httptest.NewRecorder(), req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var fairness *FairnessResponse
+			switch response := response.(type) {
+			case OpenDeckResponse:
+				fairness = response.Fairness
+			case HiddenDeckResponse:
+				fairness = response.Fairness
+			}
+			if revealed := fairness.Revealed || fairness.Order != nil || fairness.ServerSeed != ""; revealed != tt.wantRevealed {
+				t.Errorf("openDeck() fairness = %+v, wantRevealed %v", fairness, tt.wantRevealed)
+			}
+
+			req = httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/deck/%s/verify", deck.ID), nil)
+			req.SetPathValue("id", deck.ID.String())
+			req.Header.Set("Authorization", tt.authorization)
+			_, err = s.verifyDeck(httptest.NewRecorder(), req)
+			var forbiddenError *pkg.ForbiddenError
+			if errors.As(err, &forbiddenError) == tt.wantRevealed {
+				t.Errorf("verifyDeck() error = %v, wantRevealed %v", err, tt.wantRevealed)
+			}
+		})
+	}
+}
+
+func TestServer_Undo_HiddenDeck(t *testing.T) {
+	s := &Server{
+		deckProcessor: NewMemoryDeckProcessor(CryptoRandomSource{}),
+	}
+	token, err := NewOwnerToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	deck, err := s.deckProcessor.Create(context.Background(), DeckOptions{Shuffled: true, Hidden: true, OwnerToken: token, UndoDepth: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name          string
+		authorization string
+		wantForbidden bool
+	}{
+		{name: "Anonymous", authorization: "", wantForbidden: true},
+		{name: "Owner", authorization: "Bearer " + token, wantForbidden: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := s.deckProcessor.DrawCards(context.Background(), deck.ID, CardSelector{Count: 3}); err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/deck/%s/undo", deck.ID), nil)
+			req.SetPathValue("id", deck.ID.String())
+			req.Header.Set("Authorization", tt.authorization)
+
+			_, err := s.undo(httptest.NewRecorder(), req)
+			var forbiddenError *pkg.ForbiddenError
+			if errors.As(err, &forbiddenError) != tt.wantForbidden {
+				t.Errorf("undo() error = %v, wantForbidden %v", err, tt.wantForbidden)
+			}
+		})
+	}
+}
+
+func TestServer_IfMatch(t *testing.T) {
+	s := &Server{
+		deckProcessor: NewMemoryDeckProcessor(CryptoRandomSource{}),
+	}
+
+	recorder := httptest.NewRecorder()
+	created, err := s.createDeck(recorder, httptest.NewRequest(http.MethodPost, "/api/v1/deck", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	deckID := created.(CreateDeckResponse).ID.String()
+	createdETag := recorder.Header().Get("ETag")
+	if createdETag == "" {
+		t.Fatalf("createDeck() did not set ETag")
+	}
+
+	draw := func(ifMatch string) (string, error) {
+		req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/deck/%s/draw?count=1", deckID), nil)
+		req.SetPathValue("id", deckID)
+		req.Header.Set("If-Match", ifMatch)
+		recorder := httptest.NewRecorder()
+		_, err := s.drawCards(recorder, req)
+		return recorder.Header().Get("ETag"), err
+	}
+
+	drawnETag, err := draw(createdETag)
+	if err != nil {
+		t.Fatalf("drawCards() error = %v", err)
+	}
+	if drawnETag == "" || drawnETag == createdETag {
+		t.Errorf("drawCards() ETag = %q, want new entity tag", drawnETag)
+	}
+
+	var preconditionFailedError *pkg.PreconditionFailedError
+	if _, err := draw(createdETag); !errors.As(err, &preconditionFailedError) {
+		t.Errorf("drawCards() with stale If-Match error = %v, want PreconditionFailedError", err)
+	}
+	if _, err := draw("*"); err != nil {
+		t.Errorf("drawCards() with If-Match * error = %v", err)
+	}
+}
+
+func TestServer_CreateDeck_Fair(t *testing.T) {
+	s := &Server{
+		deckProcessor: NewMemoryDeckProcessor(CryptoRandomSource{}),
+	}
+
+	// the client seed must not be known before the hash of the server seed is published
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/deck?fair=true&client_seed=lucky", nil)
+	var badRequestError *pkg.BadRequestError
+	if _, err := s.createDeck(httptest.NewRecorder(), req); !errors.As(err, &badRequestError) {
+		t.Fatalf("createDeck() with client seed error = %v, want BadRequestError", err)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/deck?fair=true", nil)
+	created, err := s.createDeck(httptest.NewRecorder(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deckResponse := created.(CreateDeckResponse)
+	if deckResponse.Fairness == nil || deckResponse.Fairness.ServerSeedHash == "" || deckResponse.Fairness.Commitment != "" {
+		t.Fatalf("createDeck() fairness = %+v, want server seed hash only", deckResponse.Fairness)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/v1/deck/%s/seed?client_seed=lucky", deckResponse.ID), nil)
+	req.SetPathValue("id", deckResponse.ID.String())
+	seeded, err := s.seedDeck(httptest.NewRecorder(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fairness := seeded.(CreateDeckResponse).Fairness
+	if fairness.ServerSeedHash != deckResponse.Fairness.ServerSeedHash || fairness.Commitment == "" || fairness.ClientSeed != "lucky" {
+		t.Errorf("seedDeck() fairness = %+v", fairness)
 	}
 }
@@ -0,0 +1,227 @@
+package internal
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/prathoss/cards/pkg"
+)
+
+const (
+	// ShuffleModeRandom is a perfect shuffle using Fisher-Yates' algorithm
+	ShuffleModeRandom = "random"
+	// ShuffleModeRiffle simulates riffle shuffles following the Gilbert-Shannon-Reeds model
+	ShuffleModeRiffle = "riffle"
+	// ShuffleModeOverhand simulates overhand shuffles, packets are run from the top of the deck onto a new pile
+	ShuffleModeOverhand = "overhand"
+	// ShuffleModeCut cuts the deck once per pass
+	ShuffleModeCut = "cut"
+	// ShuffleModeFaroIn splits the deck in halves and perfectly interleaves them, the top card moves to the second position
+	ShuffleModeFaroIn = "faro-in"
+	// ShuffleModeFaroOut splits the deck in halves and perfectly interleaves them, the top card stays on top
+	ShuffleModeFaroOut = "faro-out"
+)
+
+// MaxShufflePasses limits how many times a single request repeats the shuffle
+const MaxShufflePasses = 100
+
+// riffleDefaultPasses is the number of riffle shuffles needed to mix a 52 card deck (Bayer and Diaconis)
+const riffleDefaultPasses = 7
+
+var shuffleModes = []string{
+	ShuffleModeRandom,
+	ShuffleModeRiffle,
+	ShuffleModeOverhand,
+	ShuffleModeCut,
+	ShuffleModeFaroIn,
+	ShuffleModeFaroOut,
+}
+
+// ShuffleMethod selects how cards are shuffled, the zero value is a single perfect shuffle
+type ShuffleMethod struct {
+	// Mode is one of the ShuffleMode constants, empty means ShuffleModeRandom
+	Mode string
+	// Passes is the number of times the shuffle is repeated, 0 means the default of the mode
+	Passes int
+}
+
+// IsValidShuffleMode reports whether mode is empty or one of the ShuffleMode constants
+func IsValidShuffleMode(mode string) bool {
+	if mode == "" {
+		return true
+	}
+	return slices.Contains(shuffleModes, mode)
+}
+
+// passes returns the number of passes to perform, riffles default to 7 passes and other modes to a single one
+func (m ShuffleMethod) passes() int {
+	if m.Passes > 0 {
+		return m.Passes
+	}
+	if m.Mode == ShuffleModeRiffle {
+		return riffleDefaultPasses
+	}
+	return 1
+}
+
+// ShuffleCardsWith shuffles cards in deck using the method
+func (d *Deck) ShuffleCardsWith(method ShuffleMethod, random RandomSource) error {
+	cards, err := shuffleCardsWith(d.Cards, method, d.randomSource(random))
+	if err != nil {
+		return err
+	}
+	d.Cards = cards
+	return nil
+}
+
+// shuffleCardsWith returns cards shuffled using the method, cards may be modified
+func shuffleCardsWith(cards []Card, method ShuffleMethod, random RandomSource) ([]Card, error) {
+	if !IsValidShuffleMode(method.Mode) {
+		return nil, newUnknownShuffleModeError(method.Mode)
+	}
+	if method.Passes < 0 || method.Passes > MaxShufflePasses {
+		return nil, newInvalidShufflePassesError()
+	}
+
+	var err error
+	for range method.passes() {
+		switch method.Mode {
+		case "", ShuffleModeRandom:
+			err = shuffleCards(cards, random)
+		case ShuffleModeRiffle:
+			cards, err = riffleCards(cards, random)
+		case ShuffleModeOverhand:
+			cards, err = overhandCards(cards, random)
+		case ShuffleModeCut:
+			cards, err = cutCards(cards, random)
+		case ShuffleModeFaroIn:
+			cards = faroCards(cards, false)
+		case ShuffleModeFaroOut:
+			cards = faroCards(cards, true)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return cards, nil
+}
+
+// binomialCut returns the number of heads in n fair coin flips, which is where the Gilbert-Shannon-Reeds model cuts the deck
+func binomialCut(n int, random RandomSource) (int, error) {
+	cut := 0
+	for range n {
+		flip, err := random.IntN(2)
+		if err != nil {
+			return 0, err
+		}
+		cut += flip
+	}
+	return cut, nil
+}
+
+// riffleCards performs a single Gilbert-Shannon-Reeds riffle shuffle.
+// The deck is cut binomially and the packets are interleaved,
+// the next card is dropped from a packet with probability proportional to its size.
+func riffleCards(cards []Card, random RandomSource) ([]Card, error) {
+	cut, err := binomialCut(len(cards), random)
+	if err != nil {
+		return nil, err
+	}
+
+	left, right := cards[:cut], cards[cut:]
+	riffled := make([]Card, 0, len(cards))
+	for len(left) > 0 && len(right) > 0 {
+		i, err := random.IntN(len(left) + len(right))
+		if err != nil {
+			return nil, err
+		}
+		if i < len(left) {
+			riffled = append(riffled, left[0])
+			left = left[1:]
+		} else {
+			riffled = append(riffled, right[0])
+			right = right[1:]
+		}
+	}
+	riffled = append(riffled, left...)
+	return append(riffled, right...), nil
+}
+
+// overhandCards performs a single overhand shuffle.
+// Packets of 1 to a quarter of the deck are taken from the top and each is put on top of the new pile,
+// so the order of packets is reversed while cards within a packet keep their order.
+func overhandCards(cards []Card, random RandomSource) ([]Card, error) {
+	maxPacket := max(len(cards)/4, 1)
+	shuffled := make([]Card, len(cards))
+	end := len(cards)
+	for start := 0; start < len(cards); {
+		size, err := random.IntN(maxPacket)
+		if err != nil {
+			return nil, err
+		}
+		packet := cards[start:min(start+size+1, len(cards))]
+		copy(shuffled[end-len(packet):end], packet)
+		end -= len(packet)
+		start += len(packet)
+	}
+	return shuffled, nil
+}
+
+// cutCards moves the top part of the deck under the bottom part, the cut is binomial like the one of a riffle
+func cutCards(cards []Card, random RandomSource) ([]Card, error) {
+	if len(cards) < 2 {
+		return cards, nil
+	}
+	cut, err := binomialCut(len(cards), random)
+	if err != nil {
+		return nil, err
+	}
+	cut = min(max(cut, 1), len(cards)-1)
+	return append(slices.Clone(cards[cut:]), cards[:cut]...), nil
+}
+
+// faroCards splits the deck in halves and perfectly interleaves them.
+// An out faro keeps the top card on top (the top half is larger for odd decks),
+// an in faro starts with the bottom half (the bottom half is larger for odd decks).
+func faroCards(cards []Card, out bool) []Card {
+	half := len(cards) / 2
+	if out {
+		half = (len(cards) + 1) / 2
+	}
+	first, second := cards[:half], cards[half:]
+	if !out {
+		first, second = second, first
+	}
+
+	interleaved := make([]Card, 0, len(cards))
+	for i := range first {
+		interleaved = append(interleaved, first[i])
+		if i < len(second) {
+			interleaved = append(interleaved, second[i])
+		}
+	}
+	return interleaved
+}
+
+func newUnknownShuffleModeError(mode string) *pkg.BadRequestError {
+	return pkg.NewBadRequestError(pkg.InvalidParam{
+		Name:   "mode",
+		Reason: unknownShuffleModeReason(mode),
+	})
+}
+
+func unknownShuffleModeReason(mode string) string {
+	return fmt.Sprintf("unknown shuffle mode %q, use one of: %s", mode, strings.Join(shuffleModes, ", "))
+}
+
+func newInvalidShufflePassesError() *pkg.BadRequestError {
+	return pkg.NewBadRequestError(pkg.InvalidParam{
+		Name:   "passes",
+		Reason: invalidShufflePassesReason(),
+	})
+}
+
+func invalidShufflePassesReason() string {
+	return fmt.Sprintf("passes should be between 1 and %d", MaxShufflePasses)
+}
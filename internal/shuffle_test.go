@@ -0,0 +1,227 @@
+package internal
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/prathoss/cards/pkg"
+)
+
+func spades(count int) []Card {
+	values := []string{CardValueAce, CardValueTwo, CardValueThree, CardValueFour, CardValueFive, CardValueSix}
+	cards := make([]Card, 0, count)
+	for _, value := range values[:count] {
+		cards = append(cards, Card{Value: value, Suit: CardSuitSpades})
+	}
+	return cards
+}
+
+func TestShuffleCardsWith(t *testing.T) {
+	tests := []struct {
+		name   string
+		method ShuffleMethod
+		random RandomSource
+		want   []string
+	}{
+		{
+			name:   "Random",
+			method: ShuffleMethod{Mode: ShuffleModeRandom},
+			random: NewFixtureRandomSource(0),
+			want:   []string{"2S", "3S", "4S", "5S", "6S", "AS"},
+		},
+		{
+			name:   "Riffle",
+			method: ShuffleMethod{Mode: ShuffleModeRiffle, Passes: 1},
+			// 3 heads cut the deck in halves, then cards are dropped from right, left, right, left and right
+			random: NewFixtureRandomSource(1, 1, 1, 0, 0, 0, 5, 0, 3, 0, 1),
+			want:   []string{"4S", "AS", "5S", "2S", "6S", "3S"},
+		},
+		{
+			name:   "Overhand",
+			method: ShuffleMethod{Mode: ShuffleModeOverhand},
+			// packets of a single card reverse the deck
+			random: NewFixtureRandomSource(0),
+			want:   []string{"6S", "5S", "4S", "3S", "2S", "AS"},
+		},
+		{
+			name:   "Cut",
+			method: ShuffleMethod{Mode: ShuffleModeCut},
+			random: NewFixtureRandomSource(1, 0),
+			want:   []string{"4S", "5S", "6S", "AS", "2S", "3S"},
+		},
+		{
+			name:   "Cut_NeverKeepsOrder",
+			method: ShuffleMethod{Mode: ShuffleModeCut},
+			random: NewFixtureRandomSource(0),
+			want:   []string{"2S", "3S", "4S", "5S", "6S", "AS"},
+		},
+		{
+			name:   "FaroIn",
+			method: ShuffleMethod{Mode: ShuffleModeFaroIn},
+			want:   []string{"4S", "AS", "5S", "2S", "6S", "3S"},
+		},
+		{
+			name:   "FaroOut",
+			method: ShuffleMethod{Mode: ShuffleModeFaroOut},
+			want:   []string{"AS", "4S", "2S", "5S", "3S", "6S"},
+		},
+		{
+			name:   "FaroOut_Passes",
+			method: ShuffleMethod{Mode: ShuffleModeFaroOut, Passes: 2},
+			want:   []string{"AS", "5S", "4S", "3S", "2S", "6S"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deck := Deck{Cards: spades(6)}
+			random := tt.random
+			if random == nil {
+				random = CryptoRandomSource{}
+			}
+
+			if err := deck.ShuffleCardsWith(tt.method, random); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := cardsToCodes(deck.Cards); !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShuffleCardsWith_OddDeckFaro(t *testing.T) {
+	tests := []struct {
+		mode string
+		want []string
+	}{
+		{mode: ShuffleModeFaroIn, want: []string{"3S", "AS", "4S", "2S", "5S"}},
+		{mode: ShuffleModeFaroOut, want: []string{"AS", "4S", "2S", "5S", "3S"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			deck := Deck{Cards: spades(5)}
+
+			if err := deck.ShuffleCardsWith(ShuffleMethod{Mode: tt.mode}, CryptoRandomSource{}); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := cardsToCodes(deck.Cards); !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShuffleCardsWith_EightOutFarosRestoreDeck(t *testing.T) {
+	deck, err := NewDeck(DeckOptions{}, CryptoRandomSource{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := cardsToCodes(deck.Cards)
+
+	if err := deck.ShuffleCardsWith(ShuffleMethod{Mode: ShuffleModeFaroOut, Passes: 8}, CryptoRandomSource{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := cardsToCodes(deck.Cards); !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestShuffleCardsWith_KeepsCards(t *testing.T) {
+	for _, mode := range shuffleModes {
+		t.Run(mode, func(t *testing.T) {
+			deck, err := NewDeck(DeckOptions{Decks: 2, Jokers: true}, CryptoRandomSource{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := cardsToCodes(deck.Cards)
+			slices.Sort(want)
+
+			if err := deck.ShuffleCardsWith(ShuffleMethod{Mode: mode, Passes: 3}, CryptoRandomSource{}); err != nil {
+				t.Fatal(err)
+			}
+
+			got := cardsToCodes(deck.Cards)
+			slices.Sort(got)
+			if !slices.Equal(got, want) {
+				t.Errorf("shuffle changed cards of the deck, got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestRiffleCards_TwoRisingSequences(t *testing.T) {
+	cards, err := NewDeck(DeckOptions{}, CryptoRandomSource{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	positions := make(map[string]int, len(cards.Cards))
+	for i, card := range cards.Cards {
+		positions[card.Code()] = i
+	}
+
+	riffled, err := riffleCards(cards.Cards, CryptoRandomSource{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a single riffle interleaves two packets, so the original order is split into at most two rising sequences
+	sequences := 1
+	for i := range riffled {
+		if positions[riffled[i].Code()]+1 < len(riffled) && !slices.ContainsFunc(riffled[i+1:], func(c Card) bool {
+			return positions[c.Code()] == positions[riffled[i].Code()]+1
+		}) {
+			sequences++
+		}
+	}
+	if sequences > 2 {
+		t.Errorf("riffle produced %d rising sequences, want at most 2", sequences)
+	}
+}
+
+func TestShuffleCardsWith_InvalidMethod(t *testing.T) {
+	tests := []struct {
+		name   string
+		method ShuffleMethod
+	}{
+		{name: "UnknownMode", method: ShuffleMethod{Mode: "pile"}},
+		{name: "NegativePasses", method: ShuffleMethod{Mode: ShuffleModeRiffle, Passes: -1}},
+		{name: "TooManyPasses", method: ShuffleMethod{Mode: ShuffleModeRiffle, Passes: MaxShufflePasses + 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deck := Deck{Cards: spades(6)}
+
+			err := deck.ShuffleCardsWith(tt.method, CryptoRandomSource{})
+
+			var badRequestError *pkg.BadRequestError
+			if !errors.As(err, &badRequestError) {
+				t.Errorf("expected BadRequestError, got %v", err)
+			}
+		})
+	}
+}
+
+func TestShuffleMethod_Passes(t *testing.T) {
+	tests := []struct {
+		method ShuffleMethod
+		want   int
+	}{
+		{method: ShuffleMethod{}, want: 1},
+		{method: ShuffleMethod{Mode: ShuffleModeRiffle}, want: riffleDefaultPasses},
+		{method: ShuffleMethod{Mode: ShuffleModeOverhand}, want: 1},
+		{method: ShuffleMethod{Mode: ShuffleModeRiffle, Passes: 3}, want: 3},
+	}
+
+	for _, tt := range tests {
+		if got := tt.method.passes(); got != tt.want {
+			t.Errorf("%+v.passes() = %d, want %d", tt.method, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const (
+	CardColorRed   = "RED"
+	CardColorBlack = "BLACK"
+
+	// CardRankHigh, CardRankLow and CardRankNeutral group values like the Hi-Lo card counting system
+	CardRankHigh    = "HIGH"
+	CardRankLow     = "LOW"
+	CardRankNeutral = "NEUTRAL"
+)
+
+// Color returns CardColorRed or CardColorBlack, empty for suits without a color like the Spanish ones
+func (c Card) Color() string {
+	switch c.Suit {
+	case CardSuitHearths, CardSuitDiamonds, CardSuitRed:
+		return CardColorRed
+	case CardSuitSpades, CardSuitClubs, CardSuitBlack:
+		return CardColorBlack
+	default:
+		return ""
+	}
+}
+
+// Rank returns CardRankLow for 2 to 6, CardRankNeutral for 7 to 9 and CardRankHigh for 10, faces and aces.
+// Jokers have no rank.
+func (c Card) Rank() string {
+	switch c.Value {
+	case CardValueTwo, CardValueThree, CardValueFour, CardValueFive, CardValueSix:
+		return CardRankLow
+	case CardValueSeven, CardValueEight, CardValueNine:
+		return CardRankNeutral
+	case CardValueJoker:
+		return ""
+	default:
+		return CardRankHigh
+	}
+}
+
+// cardConditionFields maps fields of a CardPredicate condition to the property of the card they compare
+var cardConditionFields = map[string]func(Card) string{
+	"suit":  func(c Card) string { return c.Suit },
+	"value": func(c Card) string { return c.Value },
+	"color": Card.Color,
+	"rank":  Card.Rank,
+	"code":  Card.Code,
+}
+
+// CardPredicate matches cards satisfying all of its conditions, it is written as comma separated field:value pairs,
+// e.g. "color:red,rank:high". The fields are suit, value, color, rank and code, values are case-insensitive.
+type CardPredicate struct {
+	Expression string
+	conditions []cardCondition
+}
+
+type cardCondition struct {
+	property func(Card) string
+	value    string
+}
+
+func ParseCardPredicate(expression string) (CardPredicate, error) {
+	predicate := CardPredicate{Expression: expression}
+	for _, condition := range strings.Split(expression, ",") {
+		field, value, ok := strings.Cut(condition, ":")
+		property, known := cardConditionFields[strings.ToLower(field)]
+		if !ok || !known || value == "" {
+			return CardPredicate{}, fmt.Errorf("condition %q should be one of suit, value, color, rank or code followed by a colon and a value", condition)
+		}
+		predicate.conditions = append(predicate.conditions, cardCondition{property: property, value: value})
+	}
+	return predicate, nil
+}
+
+func (p CardPredicate) Matches(card Card) bool {
+	for _, condition := range p.conditions {
+		if !strings.EqualFold(condition.property(card), condition.value) {
+			return false
+		}
+	}
+	return true
+}
+
+type StatsResponse struct {
+	DeckID    uuid.UUID      `json:"deck_id"`
+	Remaining int            `json:"remaining"`
+	Suits     map[string]int `json:"suits"`
+	Values    map[string]int `json:"values"`
+	Colors    map[string]int `json:"colors"`
+	Ranks     map[string]int `json:"ranks"`
+	// Probabilities holds the probability that the next card matches each of the requested predicates
+	Probabilities map[string]float64 `json:"probabilities,omitempty"`
+}
+
+// NewStatsResponse describes the composition of remaining cards of the deck without revealing their order
+func NewStatsResponse(deck Deck, predicates []CardPredicate) StatsResponse {
+	response := StatsResponse{
+		DeckID:    deck.ID,
+		Remaining: len(deck.Cards),
+		Suits:     countBySuit(deck.Cards),
+		Values:    make(map[string]int),
+		Colors:    make(map[string]int),
+		Ranks:     make(map[string]int),
+	}
+	for _, card := range deck.Cards {
+		response.Values[card.Value]++
+		if color := card.Color(); color != "" {
+			response.Colors[color]++
+		}
+		if rank := card.Rank(); rank != "" {
+			response.Ranks[rank]++
+		}
+	}
+
+	if len(predicates) > 0 {
+		response.Probabilities = make(map[string]float64, len(predicates))
+	}
+	for _, predicate := range predicates {
+		matching := 0
+		for _, card := range deck.Cards {
+			if predicate.Matches(card) {
+				matching++
+			}
+		}
+		probability := 0.0
+		if len(deck.Cards) > 0 {
+			probability = float64(matching) / float64(len(deck.Cards))
+		}
+		response.Probabilities[predicate.Expression] = probability
+	}
+	return response
+}
@@ -0,0 +1,92 @@
+package internal
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestParseCardPredicate(t *testing.T) {
+	tests := []struct {
+		expression string
+		card       Card
+		want       bool
+		wantErr    bool
+	}{
+		{expression: "suit:hearts", card: Card{Value: CardValueAce, Suit: CardSuitHearths}, want: true},
+		{expression: "suit:HEARTS", card: Card{Value: CardValueAce, Suit: CardSuitSpades}, want: false},
+		{expression: "value:ace", card: Card{Value: CardValueAce, Suit: CardSuitSpades}, want: true},
+		{expression: "code:10D", card: Card{Value: CardValueTen, Suit: CardSuitDiamonds}, want: true},
+		{expression: "color:red,rank:high", card: Card{Value: CardValueKing, Suit: CardSuitDiamonds}, want: true},
+		{expression: "color:red,rank:high", card: Card{Value: CardValueTwo, Suit: CardSuitDiamonds}, want: false},
+		{expression: "rank:low", card: Card{Value: CardValueJoker, Suit: CardSuitRed}, want: false},
+		{expression: "color:black", card: Card{Value: CardValueJoker, Suit: CardSuitBlack}, want: true},
+		{expression: "size:big", wantErr: true},
+		{expression: "suit", wantErr: true},
+		{expression: "suit:", wantErr: true},
+		{expression: "suit:hearts,", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			predicate, err := ParseCardPredicate(tt.expression)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseCardPredicate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := predicate.Matches(tt.card); got != tt.want {
+				t.Errorf("CardPredicate.Matches(%s) = %v, want %v", tt.card.Code(), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewStatsResponse(t *testing.T) {
+	deck, err := NewDeck(DeckOptions{Cards: []string{"AS", "KH", "2C", "3D", "7H", "XR"}}, CryptoRandomSource{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var predicates []CardPredicate
+	for _, expression := range []string{"color:red", "suit:spades", "value:queen"} {
+		predicate, err := ParseCardPredicate(expression)
+		if err != nil {
+			t.Fatal(err)
+		}
+		predicates = append(predicates, predicate)
+	}
+
+	stats := NewStatsResponse(deck, predicates)
+
+	if stats.Remaining != 6 {
+		t.Errorf("remaining = %d, want 6", stats.Remaining)
+	}
+	if want := map[string]int{CardSuitSpades: 1, CardSuitHearths: 2, CardSuitClubs: 1, CardSuitDiamonds: 1}; !maps.Equal(stats.Suits, want) {
+		t.Errorf("suits = %v, want %v", stats.Suits, want)
+	}
+	if want := map[string]int{CardValueAce: 1, CardValueKing: 1, CardValueTwo: 1, CardValueThree: 1, CardValueSeven: 1, CardValueJoker: 1}; !maps.Equal(stats.Values, want) {
+		t.Errorf("values = %v, want %v", stats.Values, want)
+	}
+	if want := map[string]int{CardColorRed: 4, CardColorBlack: 2}; !maps.Equal(stats.Colors, want) {
+		t.Errorf("colors = %v, want %v", stats.Colors, want)
+	}
+	if want := map[string]int{CardRankHigh: 2, CardRankLow: 2, CardRankNeutral: 1}; !maps.Equal(stats.Ranks, want) {
+		t.Errorf("ranks = %v, want %v", stats.Ranks, want)
+	}
+	if want := map[string]float64{"color:red": 4.0 / 6, "suit:spades": 1.0 / 6, "value:queen": 0}; !maps.Equal(stats.Probabilities, want) {
+		t.Errorf("probabilities = %v, want %v", stats.Probabilities, want)
+	}
+}
+
+func TestNewStatsResponse_EmptyDeck(t *testing.T) {
+	predicate, err := ParseCardPredicate("color:red")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stats := NewStatsResponse(Deck{}, []CardPredicate{predicate})
+
+	if stats.Remaining != 0 || stats.Probabilities["color:red"] != 0 {
+		t.Errorf("unexpected stats of an empty deck %+v", stats)
+	}
+}
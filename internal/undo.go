@@ -0,0 +1,96 @@
+package internal
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/prathoss/cards/pkg"
+)
+
+// MaxUndoDepth is the maximal number of operations which can be undone on a single deck
+const MaxUndoDepth = 100
+
+// DeckSnapshot holds the state of the deck before a modification, restored by Deck.Undo.
+// The bson names match Deck, so snapshots can be taken by aggregation pipelines.
+type DeckSnapshot struct {
+	Cards    []Card            `bson:"cards"`
+	Piles    map[string][]Card `bson:"piles,omitempty"`
+	Shuffled bool              `bson:"shuffled,omitempty"`
+	Seed     *uint64           `bson:"seed,omitempty"`
+}
+
+// snapshot returns the state of the deck which does not share any slices with the deck
+func (d *Deck) snapshot() DeckSnapshot {
+	return DeckSnapshot{
+		Cards:    slices.Clone(d.Cards),
+		Piles:    clonePiles(d.Piles),
+		Shuffled: d.Shuffled,
+		Seed:     d.Seed,
+	}
+}
+
+// pushSnapshot remembers the state of the deck before a modification, only UndoDepth latest states are kept
+func (d *Deck) pushSnapshot(snapshot DeckSnapshot) {
+	if d.UndoDepth <= 0 {
+		return
+	}
+	snapshots := append([]DeckSnapshot{snapshot}, d.Snapshots...)
+	d.Snapshots = snapshots[:min(len(snapshots), d.UndoDepth)]
+}
+
+// Undo restores the state of the deck before its latest modification which was not undone yet
+func (d *Deck) Undo() error {
+	if len(d.Snapshots) == 0 {
+		return newNothingToUndoError(d.ID)
+	}
+	snapshot := d.Snapshots[0]
+	d.Cards = slices.Clone(snapshot.Cards)
+	d.Piles = clonePiles(snapshot.Piles)
+	d.Shuffled = snapshot.Shuffled
+	d.Seed = snapshot.Seed
+	d.Snapshots = d.Snapshots[1:]
+	return nil
+}
+
+// rememberSnapshot pushes the snapshot taken before the modification recorded by the event, when it can be undone.
+// Draws from sealed decks drop all snapshots, undoing them would return cards whose order the caller has seen.
+func (d *Deck) rememberSnapshot(snapshot DeckSnapshot, event DeckEvent) {
+	if d.Sealed && event.draws() {
+		d.Snapshots = nil
+		return
+	}
+	if event.undoable() {
+		d.pushSnapshot(snapshot)
+	}
+}
+
+// undoable reports whether the modification recorded by the event can be undone
+func (e DeckEvent) undoable() bool {
+	// undoing the seed would break the commitment of a provably fair deck
+	return e.Type != DeckEventUndone && e.Type != DeckEventClosed && e.Type != DeckEventSeeded
+}
+
+// draws reports whether the modification recorded by the event handed out cards
+func (e DeckEvent) draws() bool {
+	switch e.Type {
+	case DeckEventDrawn, DeckEventDrawnToPile, DeckEventDealt, DeckEventDrawnFromPile:
+		return true
+	}
+	return false
+}
+
+func clonePiles(piles map[string][]Card) map[string][]Card {
+	if piles == nil {
+		return nil
+	}
+	cloned := make(map[string][]Card, len(piles))
+	for name, pile := range piles {
+		cloned[name] = slices.Clone(pile)
+	}
+	return cloned
+}
+
+func newNothingToUndoError(deckID uuid.UUID) *pkg.ConflictError {
+	return pkg.NewConflictError(fmt.Sprintf("deck with ID %s has no operation left to undo", deckID))
+}
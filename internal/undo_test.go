@@ -0,0 +1,76 @@
+package internal
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/prathoss/cards/pkg"
+)
+
+func TestDeck_Undo(t *testing.T) {
+	tests := []struct {
+		name      string
+		undoDepth int
+		undos     int
+		wantCodes []string
+		wantErr   bool
+	}{
+		{name: "LatestModification", undoDepth: 3, undos: 1, wantCodes: []string{"4S", "5S", "6S"}},
+		{name: "AllModifications", undoDepth: 3, undos: 3, wantCodes: []string{"AS", "2S", "3S", "4S", "5S", "6S"}},
+		{name: "BeyondDepth", undoDepth: 2, undos: 3, wantErr: true},
+		{name: "Disabled", undoDepth: 0, undos: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deck := Deck{Cards: spades(6), UndoDepth: tt.undoDepth}
+			for _, count := range []int{1, 2, 3} {
+				snapshot := deck.snapshot()
+				if _, err := deck.DrawCards(count); err != nil {
+					t.Fatal(err)
+				}
+				deck.pushSnapshot(snapshot)
+			}
+			if len(deck.Snapshots) > tt.undoDepth {
+				t.Fatalf("Deck.pushSnapshot() kept %d snapshots, want at most %d", len(deck.Snapshots), tt.undoDepth)
+			}
+
+			var err error
+			for range tt.undos {
+				if err = deck.Undo(); err != nil {
+					break
+				}
+			}
+			var conflictError *pkg.ConflictError
+			if tt.wantErr {
+				if !errors.As(err, &conflictError) {
+					t.Errorf("Deck.Undo() error = %v, want ConflictError", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Deck.Undo() error = %v", err)
+			}
+			if got := cardsToCodes(deck.Cards); !slices.Equal(got, tt.wantCodes) {
+				t.Errorf("Deck.Undo() cards = %v, want %v", got, tt.wantCodes)
+			}
+		})
+	}
+}
+
+func TestDeck_Undo_RestoresPiles(t *testing.T) {
+	deck := Deck{Cards: spades(4), UndoDepth: 1}
+	snapshot := deck.snapshot()
+	if err := deck.DrawToPile("discard", 2); err != nil {
+		t.Fatal(err)
+	}
+	deck.pushSnapshot(snapshot)
+
+	if err := deck.Undo(); err != nil {
+		t.Fatal(err)
+	}
+	if len(deck.Piles) != 0 || len(deck.Cards) != 4 {
+		t.Errorf("Deck.Undo() piles = %v, remaining = %d, want no piles and 4 cards", deck.Piles, len(deck.Cards))
+	}
+}
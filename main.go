@@ -10,6 +10,13 @@ import (
 
 func main() {
 	pkg.SetupLogger()
+	if len(os.Args) > 1 && os.Args[1] == "selftest" {
+		if err := internal.SelfTest(os.Args[2:], os.Stdout); err != nil {
+			slog.Error("self-test failed", pkg.Err(err))
+			os.Exit(1)
+		}
+		return
+	}
 	config, err := internal.NewConfigFromEnv()
 	if err != nil {
 		slog.Error("could not load config from environment", pkg.Err(err))
@@ -205,3 +205,115 @@ func (n *NotFoundError) WriteProblem(ctx context.Context, w http.ResponseWriter)
 	}
 	return json.NewEncoder(w).Encode(detail)
 }
+
+var _ error = &ConflictError{}
+var _ HttpProblemWriter = &ConflictError{}
+
+func NewConflictError(message string) *ConflictError {
+	return &ConflictError{
+		message: message,
+	}
+}
+
+type ConflictError struct {
+	message string
+}
+
+func (c *ConflictError) Error() string {
+	return c.message
+}
+
+func (c *ConflictError) WriteProblem(_ context.Context, w http.ResponseWriter) error {
+	w.WriteHeader(http.StatusConflict)
+	w.Header().Set("Content-Type", "application/problem+json")
+	detail := ProblemDetail{
+		Status: http.StatusConflict,
+		Type:   "https://datatracker.ietf.org/doc/html/rfc7231#section-6.5.8",
+		Title:  c.message,
+	}
+	return json.NewEncoder(w).Encode(detail)
+}
+
+var _ error = &ForbiddenError{}
+var _ HttpProblemWriter = &ForbiddenError{}
+
+func NewForbiddenError(message string) *ForbiddenError {
+	return &ForbiddenError{
+		message: message,
+	}
+}
+
+type ForbiddenError struct {
+	message string
+}
+
+func (f *ForbiddenError) Error() string {
+	return f.message
+}
+
+func (f *ForbiddenError) WriteProblem(_ context.Context, w http.ResponseWriter) error {
+	w.WriteHeader(http.StatusForbidden)
+	w.Header().Set("Content-Type", "application/problem+json")
+	detail := ProblemDetail{
+		Status: http.StatusForbidden,
+		Type:   "https://datatracker.ietf.org/doc/html/rfc7231#section-6.5.3",
+		Title:  f.message,
+	}
+	return json.NewEncoder(w).Encode(detail)
+}
+
+var _ error = &GoneError{}
+var _ HttpProblemWriter = &GoneError{}
+
+func NewGoneError(message string) *GoneError {
+	return &GoneError{
+		message: message,
+	}
+}
+
+type GoneError struct {
+	message string
+}
+
+func (g *GoneError) Error() string {
+	return g.message
+}
+
+func (g *GoneError) WriteProblem(_ context.Context, w http.ResponseWriter) error {
+	w.WriteHeader(http.StatusGone)
+	w.Header().Set("Content-Type", "application/problem+json")
+	detail := ProblemDetail{
+		Status: http.StatusGone,
+		Type:   "https://datatracker.ietf.org/doc/html/rfc7231#section-6.5.9",
+		Title:  g.message,
+	}
+	return json.NewEncoder(w).Encode(detail)
+}
+
+var _ error = &PreconditionFailedError{}
+var _ HttpProblemWriter = &PreconditionFailedError{}
+
+func NewPreconditionFailedError(message string) *PreconditionFailedError {
+	return &PreconditionFailedError{
+		message: message,
+	}
+}
+
+type PreconditionFailedError struct {
+	message string
+}
+
+func (p *PreconditionFailedError) Error() string {
+	return p.message
+}
+
+func (p *PreconditionFailedError) WriteProblem(_ context.Context, w http.ResponseWriter) error {
+	w.WriteHeader(http.StatusPreconditionFailed)
+	w.Header().Set("Content-Type", "application/problem+json")
+	detail := ProblemDetail{
+		Status: http.StatusPreconditionFailed,
+		Type:   "https://datatracker.ietf.org/doc/html/rfc7232#section-4.2",
+		Title:  p.message,
+	}
+	return json.NewEncoder(w).Encode(detail)
+}
@@ -61,6 +61,34 @@ func TestHttpHandler_ServeHTTP(t *testing.T) {
 			}),
 			wantStatus: http.StatusNotFound,
 		},
+		{
+			name: "ConflictError",
+			httpFunc: HttpHandler(func(w http.ResponseWriter, r *http.Request) (any, error) {
+				return nil, NewConflictError("conflict")
+			}),
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name: "ForbiddenError",
+			httpFunc: HttpHandler(func(w http.ResponseWriter, r *http.Request) (any, error) {
+				return nil, NewForbiddenError("forbidden")
+			}),
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name: "GoneError",
+			httpFunc: HttpHandler(func(w http.ResponseWriter, r *http.Request) (any, error) {
+				return nil, NewGoneError("gone")
+			}),
+			wantStatus: http.StatusGone,
+		},
+		{
+			name: "PreconditionFailedError",
+			httpFunc: HttpHandler(func(w http.ResponseWriter, r *http.Request) (any, error) {
+				return nil, NewPreconditionFailedError("precondition failed")
+			}),
+			wantStatus: http.StatusPreconditionFailed,
+		},
 	}
 
 	for _, tt := range tests {